@@ -0,0 +1,48 @@
+package AstUtils
+
+import "go/ast"
+
+// Closest Returns the nearest ancestor of type T on the path. The node the path belongs to isn't considered.
+func Closest[T ast.Node](path Path) (T, bool) {
+	for i := len(path) - 1; i >= 0; i-- {
+		if node, ok := path[i].Parent.(T); ok {
+			return node, true
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+// EnclosingFuncDecl Returns the nearest function declaration containing the node, or nil.
+func (p Path) EnclosingFuncDecl() *ast.FuncDecl {
+	decl, _ := Closest[*ast.FuncDecl](p)
+	return decl
+}
+
+// EnclosingTypeSpec Returns the nearest type spec containing the node, or nil.
+func (p Path) EnclosingTypeSpec() *ast.TypeSpec {
+	spec, _ := Closest[*ast.TypeSpec](p)
+	return spec
+}
+
+// EnclosingField Returns the nearest field containing the node, or nil.
+func (p Path) EnclosingField() *ast.Field {
+	field, _ := Closest[*ast.Field](p)
+	return field
+}
+
+// Depth Returns the number of ancestors of the node. The root of the traversal has depth 0.
+func (p Path) Depth() int {
+	return len(p)
+}
+
+// IsInside Reports whether any ancestor of the node is of the given kind, e.g. FuncLit. See NodeKind for the names
+// of kinds.
+func (p Path) IsInside(kind string) bool {
+	for _, step := range p {
+		if NodeKind(step.Parent) == kind {
+			return true
+		}
+	}
+	return false
+}
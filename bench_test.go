@@ -0,0 +1,600 @@
+package AstUtils
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// benchmarkFiles Parses the sources of go/types, a large real world package, for the benchmarks.
+func benchmarkFiles(b *testing.B) []*ast.File {
+	b.Helper()
+	filenames, err := filepath.Glob(filepath.Join(runtime.GOROOT(), "src", "go", "types", "*.go"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, filename := range filenames {
+		file, err := parser.ParseFile(fset, filename, nil, parser.ParseComments)
+		if err != nil {
+			b.Fatal(err)
+		}
+		files = append(files, file)
+	}
+	if len(files) == 0 {
+		b.Skip("sources of go/types not found")
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	return files
+}
+
+func isCall(node ast.Node) bool {
+	_, ok := node.(*ast.CallExpr)
+	return ok
+}
+
+func BenchmarkInspect(b *testing.B) {
+	files := benchmarkFiles(b)
+	for i := 0; i < b.N; i++ {
+		for _, file := range files {
+			ast.Inspect(file, func(node ast.Node) bool {
+				return true
+			})
+		}
+	}
+}
+
+func BenchmarkSearchNodes(b *testing.B) {
+	files := benchmarkFiles(b)
+	for i := 0; i < b.N; i++ {
+		for _, file := range files {
+			var foundNodes []*FoundNodes
+			SearchNodes(file, &foundNodes, nil, func(node *ast.Node, parents []*ast.Node, completed *bool) bool {
+				return isCall(*node)
+			}, nil)
+		}
+	}
+}
+
+func BenchmarkSearchNodesNoMatch(b *testing.B) {
+	files := benchmarkFiles(b)
+	for i := 0; i < b.N; i++ {
+		for _, file := range files {
+			var foundNodes []*FoundNodes
+			SearchNodes(file, &foundNodes, nil, func(node *ast.Node, parents []*ast.Node, completed *bool) bool {
+				return false
+			}, nil)
+		}
+	}
+}
+
+func BenchmarkSearchTree(b *testing.B) {
+	files := benchmarkFiles(b)
+	for i := 0; i < b.N; i++ {
+		for _, file := range files {
+			SearchTree(file, func(node ast.Node, path Path) (bool, Action) {
+				return isCall(node), Continue
+			})
+		}
+	}
+}
+
+func BenchmarkFindAll(b *testing.B) {
+	files := benchmarkFiles(b)
+	for i := 0; i < b.N; i++ {
+		for _, file := range files {
+			FindAll[*ast.CallExpr](file, nil)
+		}
+	}
+}
+
+func BenchmarkWalk(b *testing.B) {
+	files := benchmarkFiles(b)
+	for i := 0; i < b.N; i++ {
+		for _, file := range files {
+			for range Walk(file) {
+			}
+		}
+	}
+}
+
+func BenchmarkWalkShared(b *testing.B) {
+	files := benchmarkFiles(b)
+	for i := 0; i < b.N; i++ {
+		for _, file := range files {
+			for range WalkShared(file) {
+			}
+		}
+	}
+}
+
+func BenchmarkVisitNodes(b *testing.B) {
+	files := benchmarkFiles(b)
+	for i := 0; i < b.N; i++ {
+		for _, file := range files {
+			VisitNodes(file, VisitorFuncs{})
+		}
+	}
+}
+
+func BenchmarkBaselineSearchNodes(b *testing.B) {
+	files := benchmarkFiles(b)
+	for i := 0; i < b.N; i++ {
+		for _, file := range files {
+			var foundNodes []*FoundNodes
+			baselineSearchNodes(file, &foundNodes, nil, func(node *ast.Node, parents []*ast.Node, completed *bool) bool {
+				return isCall(*node)
+			}, nil)
+		}
+	}
+}
+
+func BenchmarkBaselineSearchNodesNoMatch(b *testing.B) {
+	files := benchmarkFiles(b)
+	for i := 0; i < b.N; i++ {
+		for _, file := range files {
+			var foundNodes []*FoundNodes
+			baselineSearchNodes(file, &foundNodes, nil, func(node *ast.Node, parents []*ast.Node, completed *bool) bool {
+				return false
+			}, nil)
+		}
+	}
+}
+
+func BenchmarkFilterWalk(b *testing.B) {
+	files := benchmarkFiles(b)
+	keep := func(node ast.Node, path Path) bool {
+		return isCall(node)
+	}
+	for i := 0; i < b.N; i++ {
+		for _, file := range files {
+			for range Filter(Walk(file), keep) {
+			}
+		}
+	}
+}
+
+func BenchmarkFilterWalkShared(b *testing.B) {
+	files := benchmarkFiles(b)
+	keep := func(node ast.Node, path Path) bool {
+		return isCall(node)
+	}
+	for i := 0; i < b.N; i++ {
+		for _, file := range files {
+			for range ClonePaths(Filter(WalkShared(file), keep)) {
+			}
+		}
+	}
+}
+
+// baselineSearchNodes is SearchNodes as it was before the traversal got rewritten, kept to benchmark against. Every
+// visited node prepends itself to a new copy of the parents.
+func baselineSearchNodes(decl ast.Node, foundNodes *[]*FoundNodes, parents []*ast.Node, searchFunction func(node *ast.Node, parents []*ast.Node, completed *bool) bool, completed *bool) {
+	if completed == nil {
+		b := false
+		completed = &b
+	}
+	if searchFunction(&decl, parents, completed) {
+		*foundNodes = append(*foundNodes, &FoundNodes{
+			Node:    &decl,
+			Parents: parents,
+		})
+	}
+	if *completed {
+		return
+	}
+	parents = append([]*ast.Node{&decl}, parents...)
+	switch decl.(type) {
+	case *ast.Comment:
+		if decl.(*ast.Comment) == nil {
+			return
+		}
+		return
+	case *ast.CommentGroup:
+		if decl.(*ast.CommentGroup) == nil {
+			return
+		}
+		if decl.(*ast.CommentGroup) == nil {
+			return
+		}
+		return
+	case *ast.Field:
+		if decl.(*ast.Field) == nil {
+			return
+		}
+		parents = append(parents, &decl)
+		baselineSearchNodes(decl.(*ast.Field).Doc, foundNodes, parents, searchFunction, completed)
+		if decl.(*ast.Field).Names != nil {
+			for i := range decl.(*ast.Field).Names {
+				baselineSearchNodes(decl.(*ast.Field).Names[i], foundNodes, parents, searchFunction, completed)
+			}
+		}
+		baselineSearchNodes(decl.(*ast.Field).Type, foundNodes, parents, searchFunction, completed)
+		baselineSearchNodes(decl.(*ast.Field).Tag, foundNodes, parents, searchFunction, completed)
+		baselineSearchNodes(decl.(*ast.Field).Comment, foundNodes, parents, searchFunction, completed)
+	case *ast.FieldList:
+		if decl.(*ast.FieldList) == nil {
+			return
+		}
+		if decl.(*ast.FieldList).List != nil {
+			for i := range decl.(*ast.FieldList).List {
+				baselineSearchNodes(decl.(*ast.FieldList).List[i], foundNodes, parents, searchFunction, completed)
+			}
+		}
+	case *ast.BadExpr:
+		if decl.(*ast.BadExpr) == nil {
+			return
+		}
+		return
+	case *ast.Ident:
+		if decl.(*ast.Ident) == nil {
+			return
+		}
+		return
+	case *ast.Ellipsis:
+		if decl.(*ast.Ellipsis) == nil {
+			return
+		}
+		baselineSearchNodes(decl.(*ast.Ellipsis).Elt, foundNodes, parents, searchFunction, completed)
+	case *ast.BasicLit:
+		if decl.(*ast.BasicLit) == nil {
+			return
+		}
+		return
+	case *ast.FuncLit:
+		if decl.(*ast.FuncLit) == nil {
+			return
+		}
+		baselineSearchNodes(decl.(*ast.FuncLit).Type, foundNodes, parents, searchFunction, completed)
+		baselineSearchNodes(decl.(*ast.FuncLit).Body, foundNodes, parents, searchFunction, completed)
+	case *ast.CompositeLit:
+		if decl.(*ast.CompositeLit) == nil {
+			return
+		}
+		baselineSearchNodes(decl.(*ast.CompositeLit).Type, foundNodes, parents, searchFunction, completed)
+		if decl.(*ast.CompositeLit).Elts != nil {
+			for i := range decl.(*ast.CompositeLit).Elts {
+				baselineSearchNodes(decl.(*ast.CompositeLit).Elts[i], foundNodes, parents, searchFunction, completed)
+			}
+		}
+	case *ast.ParenExpr:
+		if decl.(*ast.ParenExpr) == nil {
+			return
+		}
+		baselineSearchNodes(decl.(*ast.ParenExpr).X, foundNodes, parents, searchFunction, completed)
+	case *ast.SelectorExpr:
+		if decl.(*ast.SelectorExpr) == nil {
+			return
+		}
+		baselineSearchNodes(decl.(*ast.SelectorExpr).X, foundNodes, parents, searchFunction, completed)
+		baselineSearchNodes(decl.(*ast.SelectorExpr).Sel, foundNodes, parents, searchFunction, completed)
+	case *ast.IndexExpr:
+		if decl.(*ast.IndexExpr) == nil {
+			return
+		}
+		baselineSearchNodes(decl.(*ast.IndexExpr).X, foundNodes, parents, searchFunction, completed)
+		baselineSearchNodes(decl.(*ast.IndexExpr).Index, foundNodes, parents, searchFunction, completed)
+	case *ast.IndexListExpr:
+		if decl.(*ast.IndexListExpr) == nil {
+			return
+		}
+		baselineSearchNodes(decl.(*ast.IndexListExpr).X, foundNodes, parents, searchFunction, completed)
+		if decl.(*ast.IndexListExpr).Indices != nil {
+			for i := range decl.(*ast.IndexListExpr).Indices {
+				baselineSearchNodes(decl.(*ast.IndexListExpr).Indices[i], foundNodes, parents, searchFunction, completed)
+			}
+		}
+	case *ast.SliceExpr:
+		if decl.(*ast.SliceExpr) == nil {
+			return
+		}
+		baselineSearchNodes(decl.(*ast.SliceExpr).X, foundNodes, parents, searchFunction, completed)
+		baselineSearchNodes(decl.(*ast.SliceExpr).Low, foundNodes, parents, searchFunction, completed)
+		baselineSearchNodes(decl.(*ast.SliceExpr).High, foundNodes, parents, searchFunction, completed)
+		baselineSearchNodes(decl.(*ast.SliceExpr).Max, foundNodes, parents, searchFunction, completed)
+	case *ast.TypeAssertExpr:
+		if decl.(*ast.TypeAssertExpr) == nil {
+			return
+		}
+		baselineSearchNodes(decl.(*ast.TypeAssertExpr).X, foundNodes, parents, searchFunction, completed)
+		baselineSearchNodes(decl.(*ast.TypeAssertExpr).Type, foundNodes, parents, searchFunction, completed)
+	case *ast.CallExpr:
+		if decl.(*ast.CallExpr) == nil {
+			return
+		}
+		baselineSearchNodes(decl.(*ast.CallExpr).Fun, foundNodes, parents, searchFunction, completed)
+		if decl.(*ast.CallExpr).Args != nil {
+			for i := range decl.(*ast.CallExpr).Args {
+				baselineSearchNodes(decl.(*ast.CallExpr).Args[i], foundNodes, parents, searchFunction, completed)
+			}
+		}
+	case *ast.StarExpr:
+		if decl.(*ast.StarExpr) == nil {
+			return
+		}
+		baselineSearchNodes(decl.(*ast.StarExpr).X, foundNodes, parents, searchFunction, completed)
+	case *ast.UnaryExpr:
+		if decl.(*ast.UnaryExpr) == nil {
+			return
+		}
+		baselineSearchNodes(decl.(*ast.UnaryExpr).X, foundNodes, parents, searchFunction, completed)
+	case *ast.BinaryExpr:
+		if decl.(*ast.BinaryExpr) == nil {
+			return
+		}
+		baselineSearchNodes(decl.(*ast.BinaryExpr).X, foundNodes, parents, searchFunction, completed)
+		baselineSearchNodes(decl.(*ast.BinaryExpr).Y, foundNodes, parents, searchFunction, completed)
+	case *ast.KeyValueExpr:
+		if decl.(*ast.KeyValueExpr) == nil {
+			return
+		}
+		baselineSearchNodes(decl.(*ast.KeyValueExpr).Key, foundNodes, parents, searchFunction, completed)
+		baselineSearchNodes(decl.(*ast.KeyValueExpr).Value, foundNodes, parents, searchFunction, completed)
+	case *ast.ArrayType:
+		if decl.(*ast.ArrayType) == nil {
+			return
+		}
+		baselineSearchNodes(decl.(*ast.ArrayType).Elt, foundNodes, parents, searchFunction, completed)
+		baselineSearchNodes(decl.(*ast.ArrayType).Len, foundNodes, parents, searchFunction, completed)
+	case *ast.StructType:
+		if decl.(*ast.StructType) == nil {
+			return
+		}
+		baselineSearchNodes(decl.(*ast.StructType).Fields, foundNodes, parents, searchFunction, completed)
+	case *ast.FuncType:
+		if decl.(*ast.FuncType) == nil {
+			return
+		}
+		baselineSearchNodes(decl.(*ast.FuncType).TypeParams, foundNodes, parents, searchFunction, completed)
+		baselineSearchNodes(decl.(*ast.FuncType).Params, foundNodes, parents, searchFunction, completed)
+		baselineSearchNodes(decl.(*ast.FuncType).Results, foundNodes, parents, searchFunction, completed)
+	case *ast.MapType:
+		if decl.(*ast.MapType) == nil {
+			return
+		}
+		baselineSearchNodes(decl.(*ast.MapType).Key, foundNodes, parents, searchFunction, completed)
+		baselineSearchNodes(decl.(*ast.MapType).Value, foundNodes, parents, searchFunction, completed)
+	case *ast.ChanType:
+		if decl.(*ast.ChanType) == nil {
+			return
+		}
+		baselineSearchNodes(decl.(*ast.ChanType).Value, foundNodes, parents, searchFunction, completed)
+	case *ast.BadStmt:
+		if decl.(*ast.BadStmt) == nil {
+			return
+		}
+		return
+	case *ast.DeclStmt:
+		if decl.(*ast.DeclStmt) == nil {
+			return
+		}
+		baselineSearchNodes(decl.(*ast.DeclStmt).Decl, foundNodes, parents, searchFunction, completed)
+	case *ast.EmptyStmt:
+		if decl.(*ast.EmptyStmt) == nil {
+			return
+		}
+		return
+	case *ast.LabeledStmt:
+		if decl.(*ast.LabeledStmt) == nil {
+			return
+		}
+		baselineSearchNodes(decl.(*ast.LabeledStmt).Label, foundNodes, parents, searchFunction, completed)
+		baselineSearchNodes(decl.(*ast.LabeledStmt).Stmt, foundNodes, parents, searchFunction, completed)
+	case *ast.ExprStmt:
+		if decl.(*ast.ExprStmt) == nil {
+			return
+		}
+		baselineSearchNodes(decl.(*ast.ExprStmt).X, foundNodes, parents, searchFunction, completed)
+	case *ast.SendStmt:
+		if decl.(*ast.SendStmt) == nil {
+			return
+		}
+		baselineSearchNodes(decl.(*ast.SendStmt).Chan, foundNodes, parents, searchFunction, completed)
+		baselineSearchNodes(decl.(*ast.SendStmt).Value, foundNodes, parents, searchFunction, completed)
+	case *ast.IncDecStmt:
+		if decl.(*ast.IncDecStmt) == nil {
+			return
+		}
+		baselineSearchNodes(decl.(*ast.IncDecStmt).X, foundNodes, parents, searchFunction, completed)
+	case *ast.AssignStmt:
+		if decl.(*ast.AssignStmt) == nil {
+			return
+		}
+		if decl.(*ast.AssignStmt).Rhs != nil {
+			for i := range decl.(*ast.AssignStmt).Rhs {
+				baselineSearchNodes(decl.(*ast.AssignStmt).Rhs[i], foundNodes, parents, searchFunction, completed)
+			}
+		}
+		if decl.(*ast.AssignStmt).Lhs != nil {
+			for i := range decl.(*ast.AssignStmt).Lhs {
+				baselineSearchNodes(decl.(*ast.AssignStmt).Lhs[i], foundNodes, parents, searchFunction, completed)
+			}
+		}
+	case *ast.GoStmt:
+		if decl.(*ast.GoStmt) == nil {
+			return
+		}
+		baselineSearchNodes(decl.(*ast.GoStmt).Call, foundNodes, parents, searchFunction, completed)
+	case *ast.DeferStmt:
+		if decl.(*ast.DeferStmt) == nil {
+			return
+		}
+		baselineSearchNodes(decl.(*ast.DeferStmt).Call, foundNodes, parents, searchFunction, completed)
+	case *ast.ReturnStmt:
+		if decl.(*ast.ReturnStmt) == nil {
+			return
+		}
+		if decl.(*ast.ReturnStmt).Results != nil {
+			for i := range decl.(*ast.ReturnStmt).Results {
+				baselineSearchNodes(decl.(*ast.ReturnStmt).Results[i], foundNodes, parents, searchFunction, completed)
+			}
+		}
+	case *ast.BranchStmt:
+		if decl.(*ast.BranchStmt) == nil {
+			return
+		}
+		baselineSearchNodes(decl.(*ast.BranchStmt).Label, foundNodes, parents, searchFunction, completed)
+	case *ast.BlockStmt:
+		if decl.(*ast.BlockStmt) == nil {
+			return
+		}
+		if decl.(*ast.BlockStmt).List != nil {
+			for i := range decl.(*ast.BlockStmt).List {
+				baselineSearchNodes(decl.(*ast.BlockStmt).List[i], foundNodes, parents, searchFunction, completed)
+			}
+		}
+	case *ast.IfStmt:
+		baselineSearchNodes(decl.(*ast.IfStmt).Init, foundNodes, parents, searchFunction, completed)
+		baselineSearchNodes(decl.(*ast.IfStmt).Cond, foundNodes, parents, searchFunction, completed)
+		baselineSearchNodes(decl.(*ast.IfStmt).Body, foundNodes, parents, searchFunction, completed)
+		baselineSearchNodes(decl.(*ast.IfStmt).Else, foundNodes, parents, searchFunction, completed)
+	case *ast.CaseClause:
+		if decl.(*ast.CaseClause) == nil {
+			return
+		}
+		if decl.(*ast.CaseClause).List != nil {
+			for i := range decl.(*ast.CaseClause).List {
+				baselineSearchNodes(decl.(*ast.CaseClause).List[i], foundNodes, parents, searchFunction, completed)
+			}
+		}
+		if decl.(*ast.CaseClause).Body != nil {
+			for i := range decl.(*ast.CaseClause).Body {
+				baselineSearchNodes(decl.(*ast.CaseClause).Body[i], foundNodes, parents, searchFunction, completed)
+			}
+		}
+	case *ast.SwitchStmt:
+		if decl.(*ast.SwitchStmt) == nil {
+			return
+		}
+		baselineSearchNodes(decl.(*ast.SwitchStmt).Init, foundNodes, parents, searchFunction, completed)
+		baselineSearchNodes(decl.(*ast.SwitchStmt).Tag, foundNodes, parents, searchFunction, completed)
+		baselineSearchNodes(decl.(*ast.SwitchStmt).Body, foundNodes, parents, searchFunction, completed)
+	case *ast.TypeSwitchStmt:
+		if decl.(*ast.TypeSwitchStmt) == nil {
+			return
+		}
+		baselineSearchNodes(decl.(*ast.TypeSwitchStmt).Init, foundNodes, parents, searchFunction, completed)
+		baselineSearchNodes(decl.(*ast.TypeSwitchStmt).Assign, foundNodes, parents, searchFunction, completed)
+		baselineSearchNodes(decl.(*ast.TypeSwitchStmt).Body, foundNodes, parents, searchFunction, completed)
+	case *ast.CommClause:
+		if decl.(*ast.CommClause) == nil {
+			return
+		}
+		baselineSearchNodes(decl.(*ast.CommClause).Comm, foundNodes, parents, searchFunction, completed)
+		if decl.(*ast.CommClause).Body != nil {
+			for i := range decl.(*ast.CommClause).Body {
+				baselineSearchNodes(decl.(*ast.CommClause).Body[i], foundNodes, parents, searchFunction, completed)
+			}
+		}
+	case *ast.SelectStmt:
+		if decl.(*ast.SelectStmt) == nil {
+			return
+		}
+		baselineSearchNodes(decl.(*ast.SelectStmt).Body, foundNodes, parents, searchFunction, completed)
+	case *ast.ForStmt:
+		if decl.(*ast.ForStmt) == nil {
+			return
+		}
+		baselineSearchNodes(decl.(*ast.ForStmt).Init, foundNodes, parents, searchFunction, completed)
+		baselineSearchNodes(decl.(*ast.ForStmt).Cond, foundNodes, parents, searchFunction, completed)
+		baselineSearchNodes(decl.(*ast.ForStmt).Post, foundNodes, parents, searchFunction, completed)
+		baselineSearchNodes(decl.(*ast.ForStmt).Body, foundNodes, parents, searchFunction, completed)
+	case *ast.RangeStmt:
+		if decl.(*ast.RangeStmt) == nil {
+			return
+		}
+		baselineSearchNodes(decl.(*ast.RangeStmt).Key, foundNodes, parents, searchFunction, completed)
+		baselineSearchNodes(decl.(*ast.RangeStmt).Value, foundNodes, parents, searchFunction, completed)
+		baselineSearchNodes(decl.(*ast.RangeStmt).X, foundNodes, parents, searchFunction, completed)
+		baselineSearchNodes(decl.(*ast.RangeStmt).Body, foundNodes, parents, searchFunction, completed)
+	case *ast.ImportSpec:
+		if decl.(*ast.ImportSpec) == nil {
+			return
+		}
+		baselineSearchNodes(decl.(*ast.ImportSpec).Doc, foundNodes, parents, searchFunction, completed)
+		baselineSearchNodes(decl.(*ast.ImportSpec).Name, foundNodes, parents, searchFunction, completed)
+		baselineSearchNodes(decl.(*ast.ImportSpec).Path, foundNodes, parents, searchFunction, completed)
+		baselineSearchNodes(decl.(*ast.ImportSpec).Comment, foundNodes, parents, searchFunction, completed)
+	case *ast.ValueSpec:
+		if decl.(*ast.ValueSpec) == nil {
+			return
+		}
+		baselineSearchNodes(decl.(*ast.ValueSpec).Doc, foundNodes, parents, searchFunction, completed)
+		if decl.(*ast.ValueSpec).Names != nil {
+			for i := range decl.(*ast.ValueSpec).Names {
+				baselineSearchNodes(decl.(*ast.ValueSpec).Names[i], foundNodes, parents, searchFunction, completed)
+			}
+		}
+		baselineSearchNodes(decl.(*ast.ValueSpec).Type, foundNodes, parents, searchFunction, completed)
+		if decl.(*ast.ValueSpec).Values != nil {
+			for i := range decl.(*ast.ValueSpec).Values {
+				baselineSearchNodes(decl.(*ast.ValueSpec).Values[i], foundNodes, parents, searchFunction, completed)
+			}
+		}
+		baselineSearchNodes(decl.(*ast.ValueSpec).Comment, foundNodes, parents, searchFunction, completed)
+	case *ast.TypeSpec:
+		if decl.(*ast.TypeSpec) == nil {
+			return
+		}
+		baselineSearchNodes(decl.(*ast.TypeSpec).Doc, foundNodes, parents, searchFunction, completed)
+		baselineSearchNodes(decl.(*ast.TypeSpec).Name, foundNodes, parents, searchFunction, completed)
+		baselineSearchNodes(decl.(*ast.TypeSpec).TypeParams, foundNodes, parents, searchFunction, completed)
+		baselineSearchNodes(decl.(*ast.TypeSpec).Type, foundNodes, parents, searchFunction, completed)
+		baselineSearchNodes(decl.(*ast.TypeSpec).Comment, foundNodes, parents, searchFunction, completed)
+	case *ast.BadDecl:
+		if decl.(*ast.BadDecl) == nil {
+			return
+		}
+		return
+	case *ast.GenDecl:
+		if decl.(*ast.GenDecl) == nil {
+			return
+		}
+		baselineSearchNodes(decl.(*ast.GenDecl).Doc, foundNodes, parents, searchFunction, completed)
+		if decl.(*ast.GenDecl).Specs != nil {
+			for i := range decl.(*ast.GenDecl).Specs {
+				baselineSearchNodes(decl.(*ast.GenDecl).Specs[i], foundNodes, parents, searchFunction, completed)
+			}
+		}
+	case *ast.FuncDecl:
+		if decl.(*ast.FuncDecl) == nil {
+			return
+		}
+		baselineSearchNodes(decl.(*ast.FuncDecl).Doc, foundNodes, parents, searchFunction, completed)
+		baselineSearchNodes(decl.(*ast.FuncDecl).Recv, foundNodes, parents, searchFunction, completed)
+		baselineSearchNodes(decl.(*ast.FuncDecl).Name, foundNodes, parents, searchFunction, completed)
+		baselineSearchNodes(decl.(*ast.FuncDecl).Type, foundNodes, parents, searchFunction, completed)
+		baselineSearchNodes(decl.(*ast.FuncDecl).Body, foundNodes, parents, searchFunction, completed)
+	case *ast.File:
+		if decl.(*ast.File) == nil {
+			return
+		}
+		baselineSearchNodes(decl.(*ast.File).Doc, foundNodes, parents, searchFunction, completed)
+		baselineSearchNodes(decl.(*ast.File).Name, foundNodes, parents, searchFunction, completed)
+		if decl.(*ast.File).Decls != nil {
+			for i := range decl.(*ast.File).Decls {
+				baselineSearchNodes(decl.(*ast.File).Decls[i], foundNodes, parents, searchFunction, completed)
+			}
+		}
+		if decl.(*ast.File).Imports != nil {
+			for i := range decl.(*ast.File).Imports {
+				baselineSearchNodes(decl.(*ast.File).Imports[i], foundNodes, parents, searchFunction, completed)
+			}
+		}
+		if decl.(*ast.File).Unresolved != nil {
+			for i := range decl.(*ast.File).Unresolved {
+				baselineSearchNodes(decl.(*ast.File).Unresolved[i], foundNodes, parents, searchFunction, completed)
+			}
+		}
+		if decl.(*ast.File).Comments != nil {
+			for i := range decl.(*ast.File).Comments {
+				baselineSearchNodes(decl.(*ast.File).Comments[i], foundNodes, parents, searchFunction, completed)
+			}
+		}
+	}
+}
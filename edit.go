@@ -0,0 +1,342 @@
+package AstUtils
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"reflect"
+)
+
+// optionalFields holds the fields that may be nil in a valid tree, and therefore can be removed by RemoveNode.
+var optionalFields = map[string]bool{
+	"Field.Doc":           true,
+	"Field.Tag":           true,
+	"Field.Comment":       true,
+	"FuncType.TypeParams": true,
+	"FuncType.Results":    true,
+	"CompositeLit.Type":   true,
+	"SliceExpr.Low":       true,
+	"SliceExpr.High":      true,
+	"SliceExpr.Max":       true,
+	"ArrayType.Len":       true,
+	"BranchStmt.Label":    true,
+	"IfStmt.Init":         true,
+	"IfStmt.Else":         true,
+	"SwitchStmt.Init":     true,
+	"SwitchStmt.Tag":      true,
+	"TypeSwitchStmt.Init": true,
+	"ForStmt.Init":        true,
+	"ForStmt.Cond":        true,
+	"ForStmt.Post":        true,
+	"RangeStmt.Key":       true,
+	"RangeStmt.Value":     true,
+	"ImportSpec.Doc":      true,
+	"ImportSpec.Name":     true,
+	"ImportSpec.Comment":  true,
+	"ValueSpec.Doc":       true,
+	"ValueSpec.Type":      true,
+	"ValueSpec.Comment":   true,
+	"TypeSpec.Doc":        true,
+	"TypeSpec.TypeParams": true,
+	"TypeSpec.Comment":    true,
+	"GenDecl.Doc":         true,
+	"FuncDecl.Doc":        true,
+	"FuncDecl.Recv":       true,
+	"FuncDecl.Body":       true,
+	"File.Doc":            true,
+}
+
+// nonEmptyLists holds the list fields that must keep at least one element.
+var nonEmptyLists = map[string]bool{
+	"ValueSpec.Names":       true,
+	"AssignStmt.Lhs":        true,
+	"AssignStmt.Rhs":        true,
+	"IndexListExpr.Indices": true,
+	"CaseClause.List":       true,
+}
+
+// ReplaceNode Replaces the node the path points to with node. Works for any slot, single fields as well as list
+// elements. Returns an error if node can't be stored in the slot, e.g. a statement in an expression slot.
+func ReplaceNode(path Path, node ast.Node) error {
+	slot, step, err := slotOf(path)
+	if err != nil {
+		return err
+	}
+	if isNilNode(node) {
+		return fmt.Errorf("can't replace %s with nil, use RemoveNode instead", stepName(step))
+	}
+	v := reflect.ValueOf(node)
+	if !v.Type().AssignableTo(slot.Type()) {
+		return fmt.Errorf("can't store %T in %s", node, stepName(step))
+	}
+	slot.Set(v)
+	return nil
+}
+
+// RemoveNode Removes the node the path points to. List elements like statements, declarations, specs and fields are
+// deleted from their list, single fields are set to nil if they are optional. Paths pointing to later elements of the
+// same list are invalidated by the removal. Returns an error instead of removing the node, if the tree would become
+// invalid or change its meaning, e.g. for the only expression of a case clause, which would turn into a default.
+func RemoveNode(path Path) error {
+	if len(path) == 0 {
+		return fmt.Errorf("can't remove the root of the traversal")
+	}
+	step := path[len(path)-1]
+	field, err := fieldOf(step)
+	if err != nil {
+		return err
+	}
+	name := stepName(step)
+	if step.Index < 0 {
+		if !optionalFields[name] {
+			return fmt.Errorf("can't remove required field %s", name)
+		}
+		if err := checkFieldRemoval(path); err != nil {
+			return err
+		}
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+	if step.Index >= field.Len() {
+		return fmt.Errorf("index %d of %s out of range", step.Index, name)
+	}
+	if nonEmptyLists[name] && field.Len() == 1 {
+		return fmt.Errorf("can't remove the last element of %s", name)
+	}
+	if err := checkRemoval(path, field.Len()); err != nil {
+		return err
+	}
+	field.Set(reflect.AppendSlice(field.Slice(0, step.Index), field.Slice(step.Index+1, field.Len())))
+	return nil
+}
+
+// checkFieldRemoval Returns an error, if removing the optional field the path points to leaves an invalid tree or
+// changes the meaning of the parent.
+func checkFieldRemoval(path Path) error {
+	switch parent := path.Parent().(type) {
+	case *ast.CompositeLit:
+		outerPath, outer, _, ok := enclosingLiteral(path[:len(path)-1])
+		if !ok {
+			return fmt.Errorf("can't remove the type of a composite literal, that isn't an element of another literal")
+		}
+		switch literalType(outerPath, outer).(type) {
+		case *ast.ArrayType, *ast.MapType:
+		default:
+			return fmt.Errorf("can't remove the type of a composite literal, that isn't an element of an array, slice or map literal")
+		}
+	case *ast.RangeStmt:
+		if path[len(path)-1].Field == "Key" && parent.Value != nil {
+			return fmt.Errorf("can't remove the key of a range statement with a value, remove the value first")
+		}
+	}
+	return nil
+}
+
+// enclosingLiteral Returns the composite literal the literal at path is an element, key or value of, together with
+// its path and whether the literal at path is a key.
+func enclosingLiteral(path Path) (Path, *ast.CompositeLit, bool, bool) {
+	if len(path) == 0 {
+		return nil, nil, false, false
+	}
+	step := path[len(path)-1]
+	switch parent := step.Parent.(type) {
+	case *ast.CompositeLit:
+		return path[:len(path)-1], parent, false, step.Field == "Elts"
+	case *ast.KeyValueExpr:
+		if len(path) < 2 || path[len(path)-2].Field != "Elts" {
+			return nil, nil, false, false
+		}
+		outer, ok := path[len(path)-2].Parent.(*ast.CompositeLit)
+		return path[:len(path)-2], outer, step.Field == "Key", ok
+	}
+	return nil, nil, false, false
+}
+
+// literalType Returns the type of the composite literal at path, which is the element, key or value type of the
+// enclosing literal if the type is elided. Returns nil if the type can't be determined syntactically.
+func literalType(path Path, lit *ast.CompositeLit) ast.Expr {
+	if lit.Type != nil {
+		return ast.Unparen(lit.Type)
+	}
+	outerPath, outer, key, ok := enclosingLiteral(path)
+	if !ok {
+		return nil
+	}
+	var elt ast.Expr
+	switch t := literalType(outerPath, outer).(type) {
+	case *ast.ArrayType:
+		elt = t.Elt
+	case *ast.MapType:
+		elt = t.Value
+		if key {
+			elt = t.Key
+		}
+	default:
+		return nil
+	}
+	if star, ok := ast.Unparen(elt).(*ast.StarExpr); ok {
+		elt = star.X
+	}
+	return ast.Unparen(elt)
+}
+
+// checkRemoval Returns an error, if removing the list element the path points to leaves an invalid tree, besides the
+// lists that must not become empty. length is the length of the list before the removal.
+func checkRemoval(path Path, length int) error {
+	switch parent := path.Parent().(type) {
+	case *ast.GenDecl:
+		if length == 1 && !parent.Lparen.IsValid() {
+			return fmt.Errorf("can't remove the only spec of a %s declaration without parentheses, remove the declaration instead", parent.Tok)
+		}
+	case *ast.Field:
+		if length > 1 {
+			return nil
+		}
+		// The field becomes unnamed. path[len(path)-3] steps from the node holding the field list to the list.
+		if len(path) < 3 {
+			if !isEmbeddable(parent.Type) {
+				return fmt.Errorf("can't remove the only name of a field of type %s", types.ExprString(parent.Type))
+			}
+			return nil
+		}
+		list := path[len(path)-2].Parent.(*ast.FieldList)
+		switch owner := path[len(path)-3]; owner.Parent.(type) {
+		case *ast.StructType:
+			if !isEmbeddable(parent.Type) {
+				return fmt.Errorf("can't remove the only name of a field of type %s, it can't be embedded", types.ExprString(parent.Type))
+			}
+		case *ast.FuncType, *ast.FuncDecl:
+			if owner.Field == "TypeParams" {
+				return fmt.Errorf("can't remove the only name of a type parameter")
+			}
+			if len(list.List) > 1 {
+				return fmt.Errorf("can't remove the only name of a parameter, the %s would mix named and unnamed parameters", owner.Field)
+			}
+		default:
+			return fmt.Errorf("can't remove the only name of a field of %s.%s", NodeKind(owner.Parent), owner.Field)
+		}
+	}
+	return nil
+}
+
+// isEmbeddable Reports whether expr may be the type of an embedded struct field, which is a type name or a pointer to
+// a type name, possibly with type arguments.
+func isEmbeddable(expr ast.Expr) bool {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	switch e := expr.(type) {
+	case *ast.IndexExpr:
+		expr = e.X
+	case *ast.IndexListExpr:
+		expr = e.X
+	}
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return true
+	case *ast.SelectorExpr:
+		_, ok := e.X.(*ast.Ident)
+		return ok
+	}
+	return false
+}
+
+// InsertBefore Inserts nodes in front of the list element the path points to. Paths pointing to the same or later
+// elements of the list are invalidated by the insertion.
+func InsertBefore(path Path, nodes ...ast.Node) error {
+	if len(path) == 0 {
+		return fmt.Errorf("can't insert next to the root of the traversal")
+	}
+	return insertAt(path[len(path)-1], 0, nodes)
+}
+
+// InsertAfter Inserts nodes behind the list element the path points to. Paths pointing to later elements of the list
+// are invalidated by the insertion.
+func InsertAfter(path Path, nodes ...ast.Node) error {
+	if len(path) == 0 {
+		return fmt.Errorf("can't insert next to the root of the traversal")
+	}
+	return insertAt(path[len(path)-1], 1, nodes)
+}
+
+// insertAt Inserts nodes into the list of step, at the index of step plus offset.
+func insertAt(step PathStep, offset int, nodes []ast.Node) error {
+	field, err := fieldOf(step)
+	if err != nil {
+		return err
+	}
+	if step.Index < 0 {
+		return fmt.Errorf("%s is not a list", stepName(step))
+	}
+	if step.Index >= field.Len() {
+		return fmt.Errorf("index %d of %s out of range", step.Index, stepName(step))
+	}
+	elements := reflect.MakeSlice(field.Type(), 0, len(nodes))
+	for _, node := range nodes {
+		if isNilNode(node) {
+			return fmt.Errorf("can't insert nil into %s", stepName(step))
+		}
+		v := reflect.ValueOf(node)
+		if !v.Type().AssignableTo(field.Type().Elem()) {
+			return fmt.Errorf("can't insert %T into %s", node, stepName(step))
+		}
+		elements = reflect.Append(elements, v)
+	}
+	at := step.Index + offset
+	list := reflect.MakeSlice(field.Type(), 0, field.Len()+len(nodes))
+	list = reflect.AppendSlice(list, field.Slice(0, at))
+	list = reflect.AppendSlice(list, elements)
+	list = reflect.AppendSlice(list, field.Slice(at, field.Len()))
+	field.Set(list)
+	return nil
+}
+
+// slotOf Returns the settable value holding the node the path points to.
+func slotOf(path Path) (reflect.Value, PathStep, error) {
+	if len(path) == 0 {
+		return reflect.Value{}, PathStep{}, fmt.Errorf("can't edit the root of the traversal")
+	}
+	step := path[len(path)-1]
+	field, err := fieldOf(step)
+	if err != nil {
+		return reflect.Value{}, step, err
+	}
+	if step.Index < 0 {
+		return field, step, nil
+	}
+	if field.Kind() != reflect.Slice {
+		return reflect.Value{}, step, fmt.Errorf("%s is not a list", stepName(step))
+	}
+	if step.Index >= field.Len() {
+		return reflect.Value{}, step, fmt.Errorf("index %d of %s out of range", step.Index, stepName(step))
+	}
+	return field.Index(step.Index), step, nil
+}
+
+// fieldOf Returns the settable field of the parent named by step.
+func fieldOf(step PathStep) (reflect.Value, error) {
+	if isNilNode(step.Parent) {
+		return reflect.Value{}, fmt.Errorf("path step %s has no parent", step.Field)
+	}
+	parent := reflect.ValueOf(step.Parent)
+	if parent.Kind() != reflect.Pointer || parent.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("unsupported parent %T", step.Parent)
+	}
+	field := parent.Elem().FieldByName(step.Field)
+	if !field.IsValid() {
+		return reflect.Value{}, fmt.Errorf("%T has no field %s", step.Parent, step.Field)
+	}
+	return field, nil
+}
+
+// stepName Returns the name of the field of step in the form Type.Field, e.g. BlockStmt.List.
+func stepName(step PathStep) string {
+	t := reflect.TypeOf(step.Parent)
+	if t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil {
+		return step.Field
+	}
+	return t.Name() + "." + step.Field
+}
@@ -0,0 +1,219 @@
+package AstUtils
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+func TestRemoveNode(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		ident string
+		// query selects the removed node instead of ident, if set
+		query string
+		// spec removes the spec declaring ident instead of the identifier itself
+		spec    bool
+		wantErr bool
+	}{
+		{name: "only name of struct field", src: "type S struct{ X []int }", ident: "X", wantErr: true},
+		{name: "only name of embeddable field", src: "type S struct{ X Base }", ident: "X"},
+		{name: "only name of pointer field", src: "type S struct{ X *pkg.Base }", ident: "X"},
+		{name: "only name of generic field", src: "type S struct{ X Base[int] }", ident: "X"},
+		{name: "one of several names", src: "type S struct{ X, Y []int }", ident: "X"},
+		{name: "only name of one parameter", src: "func h(a int, b string) {}", ident: "a", wantErr: true},
+		{name: "only name of the only parameter", src: "func h(a int) {}", ident: "a"},
+		{name: "one of several parameter names", src: "func h(a, b int) {}", ident: "a"},
+		{name: "only name of type parameter", src: "func h[T any]() {}", ident: "T", wantErr: true},
+		{name: "only name of method", src: "type I interface{ M() }", ident: "M", wantErr: true},
+		{name: "only spec without parentheses", src: "var a = 1", ident: "a", spec: true, wantErr: true},
+		{name: "only spec with parentheses", src: "var (\n\ta = 1\n)", ident: "a", spec: true},
+		{name: "one of several specs", src: "var (\n\ta = 1\n\tb = 2\n)", ident: "a", spec: true},
+		{name: "only name of value spec", src: "var a = 1", ident: "a", wantErr: true},
+		{name: "only expression of case", src: "func f(x int) {\n\tswitch x {\n\tcase 1:\n\t}\n}", query: "CaseClause > BasicLit", wantErr: true},
+		{name: "one of several expressions of case", src: "func f(x int) {\n\tswitch x {\n\tcase 1, 2:\n\t}\n}", query: "CaseClause > BasicLit"},
+		{name: "communication of case", src: "func f(c chan int) {\n\tselect {\n\tcase <-c:\n\t}\n}", query: "CommClause > ExprStmt", wantErr: true},
+		{name: "type of top level literal", src: "var x = T{{1}}", query: "ValueSpec > CompositeLit > Ident", wantErr: true},
+		{name: "type of slice element", src: "var x = []T{T{1}}", query: "CompositeLit > CompositeLit > Ident"},
+		{name: "type of map value", src: "var x = map[string]T{\"a\": T{1}}", query: "KeyValueExpr > CompositeLit > Ident"},
+		{name: "type of map key", src: "var x = map[T]int{T{1}: 1}", query: "KeyValueExpr > CompositeLit > Ident"},
+		{name: "type of nested element", src: "var x = [][]T{{T{1}}}", query: "CompositeLit > CompositeLit > CompositeLit > Ident"},
+		{name: "type of pointer element", src: "var x = []*T{{1}, &T{2}}", query: "UnaryExpr > CompositeLit > Ident", wantErr: true},
+		{name: "type of struct field value", src: "var x = S{F: T{1}}", query: "KeyValueExpr > CompositeLit > Ident[Name=T]", wantErr: true},
+		{name: "type of type assertion", src: "var y = x.(int)", query: "TypeAssertExpr > Ident[Name=int]", wantErr: true},
+		{name: "key of range with value", src: "func f(m map[int]int) {\n\tfor k, v := range m {\n\t\t_, _ = k, v\n\t}\n}", query: "RangeStmt > Ident[Name=k]", wantErr: true},
+		{name: "value of range", src: "func f(m map[int]int) {\n\tfor k, v := range m {\n\t\t_ = k\n\t}\n}", query: "RangeStmt > Ident[Name=v]"},
+		{name: "key of range without value", src: "func f(m map[int]int) {\n\tfor k := range m {\n\t}\n}", query: "RangeStmt > Ident[Name=k]"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fset := token.NewFileSet()
+			file, err := parser.ParseFile(fset, "test.go", "package p\n\n"+test.src+"\n", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var path Path
+			if test.query != "" {
+				found := MustCompileQuery(test.query).Search(file)
+				if len(found) == 0 {
+					t.Fatalf("%s not found", test.query)
+				}
+				path = found[0].Path
+			} else {
+				match, ok := FindFirst(file, func(ident *ast.Ident, path Path) bool {
+					return ident.Name == test.ident
+				})
+				if !ok {
+					t.Fatalf("%s not found", test.ident)
+				}
+				path = match.Path
+			}
+			if test.spec {
+				for len(path) > 0 {
+					if _, ok := path.Parent().(*ast.GenDecl); ok {
+						break
+					}
+					path = path[:len(path)-1]
+				}
+			}
+			err = RemoveNode(path)
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("removal succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if err := format.Node(&buf, fset, file); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := parser.ParseFile(token.NewFileSet(), "result.go", buf.Bytes(), 0); err != nil {
+				t.Fatalf("result doesn't parse: %v\n%s", err, buf.String())
+			}
+		})
+	}
+}
+
+// editFile Parses the declarations in src and returns the file together with the path of the first node matching
+// query.
+func editFile(t *testing.T, src, query string) (*token.FileSet, *ast.File, Path) {
+	t.Helper()
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "test.go", "package p\n\n"+src+"\n", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := MustCompileQuery(query).Search(file)
+	if len(found) == 0 {
+		t.Fatalf("%s not found", query)
+	}
+	return fset, file, found[0].Path
+}
+
+// formatFile Returns the formatted source of file without the package clause.
+func formatFile(t *testing.T, fset *token.FileSet, file *ast.File) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, file); err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSpace(strings.TrimPrefix(buf.String(), "package p\n"))
+}
+
+func TestReplaceNode(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		query   string
+		node    ast.Node
+		want    string
+		wantErr bool
+	}{
+		{name: "expression", src: "var a = b", query: "ValueSpec > Ident[Name=b]", node: &ast.BasicLit{Kind: token.INT, Value: "1"}, want: "var a = 1"},
+		{name: "list element", src: "var a, b = 1, 2", query: "ValueSpec > Ident[Name=b]", node: ast.NewIdent("c"), want: "var a, c = 1, 2"},
+		{name: "statement", src: "func f() {\n\tg()\n}", query: "ExprStmt", node: &ast.ReturnStmt{}, want: "func f() {\n\treturn\n"},
+		{name: "statement into expression", src: "var a = b", query: "ValueSpec > Ident[Name=b]", node: &ast.ReturnStmt{}, wantErr: true},
+		{name: "expression into identifier", src: "var a = b", query: "ValueSpec > Ident[Name=a]", node: &ast.BasicLit{Kind: token.INT, Value: "1"}, wantErr: true},
+		{name: "nil", src: "var a = b", query: "ValueSpec > Ident[Name=b]", node: (*ast.Ident)(nil), wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fset, file, path := editFile(t, test.src, test.query)
+			err := ReplaceNode(path, test.node)
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("replacement succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := formatFile(t, fset, file); !strings.HasPrefix(got, test.want) {
+				t.Errorf("got\n%s\nwant\n%s", got, test.want)
+			}
+		})
+	}
+	if err := ReplaceNode(nil, ast.NewIdent("a")); err == nil {
+		t.Error("replaced the root")
+	}
+}
+
+func TestInsert(t *testing.T) {
+	body := "func f() {\n\ta()\n\tb()\n}"
+	stmt := func(name string) ast.Node {
+		return &ast.ExprStmt{X: &ast.CallExpr{Fun: ast.NewIdent(name)}}
+	}
+	tests := []struct {
+		name    string
+		src     string
+		query   string
+		after   bool
+		nodes   []ast.Node
+		want    string
+		wantErr bool
+	}{
+		{name: "before first", src: body, query: "ExprStmt", nodes: []ast.Node{stmt("x"), stmt("y")}, want: "x()\n\ty()\n\ta()\n\tb()"},
+		{name: "after first", src: body, query: "ExprStmt", after: true, nodes: []ast.Node{stmt("x")}, want: "a()\n\tx()\n\tb()"},
+		{name: "after last", src: body, query: "ExprStmt:last", after: true, nodes: []ast.Node{stmt("x")}, want: "a()\n\tb()\n\tx()"},
+		{name: "field", src: "type T struct{ A int }", query: "Field", after: true, nodes: []ast.Node{&ast.Field{Names: []*ast.Ident{ast.NewIdent("B")}, Type: ast.NewIdent("string")}}, want: "type T struct {\n\tA int\n\tB string\n}"},
+		{name: "not a list", src: "var a = -b", query: "UnaryExpr > Ident", nodes: []ast.Node{ast.NewIdent("c")}, wantErr: true},
+		{name: "wrong type", src: body, query: "ExprStmt", nodes: []ast.Node{ast.NewIdent("c")}, wantErr: true},
+		{name: "nil", src: body, query: "ExprStmt", nodes: []ast.Node{nil}, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			query := strings.TrimSuffix(test.query, ":last")
+			fset, file, path := editFile(t, test.src, query)
+			if query != test.query {
+				found := MustCompileQuery(query).Search(file)
+				path = found[len(found)-1].Path
+			}
+			insert := InsertBefore
+			if test.after {
+				insert = InsertAfter
+			}
+			err := insert(path, test.nodes...)
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("insertion succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := formatFile(t, fset, file); !strings.Contains(got, test.want) {
+				t.Errorf("got\n%s\nwant\n%s", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package AstUtils
+
+import (
+	"context"
+	"go/ast"
+)
+
+// Match holds a typed node found by FindAll or FindFirst, together with its path.
+type Match[T ast.Node] struct {
+	Node T
+	Path Path
+}
+
+// FindAll Returns all nodes of type T below and including root, for which pred returns true. A nil pred matches every
+// node of type T. The path passed to pred is only valid during the call, the paths of the matches are copies.
+func FindAll[T ast.Node](root ast.Node, pred func(node T, path Path) bool, opts ...SearchOption) []Match[T] {
+	matches, _ := FindAllContext(context.Background(), root, pred, opts...)
+	return matches
+}
+
+// FindAllContext Works like FindAll, but terminates the search once ctx is done. Returns the matches found until then
+// and ctx.Err() if the search was cancelled.
+func FindAllContext[T ast.Node](ctx context.Context, root ast.Node, pred func(node T, path Path) bool, opts ...SearchOption) ([]Match[T], error) {
+	var matches []Match[T]
+	_, err := walkPath(ctx, root, Path{}, func(n ast.Node, path Path) bool {
+		if node, ok := n.(T); ok && (pred == nil || pred(node, path)) {
+			matches = append(matches, Match[T]{
+				Node: node,
+				Path: path.Clone(),
+			})
+		}
+		return true
+	}, opts)
+	return matches, err
+}
+
+// FindFirst Returns the first node of type T in traversal order, for which pred returns true. The traversal is
+// terminated as soon as the node is found. A nil pred matches every node of type T.
+func FindFirst[T ast.Node](root ast.Node, pred func(node T, path Path) bool, opts ...SearchOption) (Match[T], bool) {
+	for n, path := range WalkShared(root, opts...) {
+		if node, ok := n.(T); ok && (pred == nil || pred(node, path)) {
+			return Match[T]{
+				Node: node,
+				Path: path.Clone(),
+			}, true
+		}
+	}
+	return Match[T]{}, false
+}
@@ -1,6 +1,7 @@
 package AstUtils
 
 import (
+	"context"
 	"errors"
 	"go/ast"
 	"go/parser"
@@ -46,31 +47,30 @@ func PreviouslyModified(file *ast.File, searchString string) bool {
 }
 
 func AddMissingImports(file *ast.File, imports []string) {
+	_ = AddMissingImportsContext(context.Background(), file, imports)
+}
+
+// AddMissingImportsContext Works like AddMissingImports, but stops once ctx is done. The file isn't modified, if the
+// search for existing imports was cancelled. Returns ctx.Err() in that case.
+func AddMissingImportsContext(ctx context.Context, file *ast.File, imports []string) error {
 	if imports == nil || len(imports) == 0 {
-		return
+		return nil
 	}
 	requiredImports := map[string]bool{}
-	var importSpecs []*FoundNodes
-	var completed bool
 	var specs []ast.Spec
-	SearchNodes(file, &importSpecs, []*ast.Node{}, func(n *ast.Node, parents []*ast.Node, completed *bool) bool {
-		if _, ok := (*n).(*ast.ImportSpec); ok {
-			return true
-		}
-		return false
-	}, &completed)
 
-	for i, spec := range importSpecs {
-		imports = append(imports)
-		requiredImports[strings.ReplaceAll((*spec.Node).(*ast.ImportSpec).Path.Value, "\"", "")] = true
-		for i2, parent := range spec.Parents {
-			if _, ok := (*parent).(*ast.GenDecl); ok {
-				for i3, _ := range file.Decls {
-					if (*importSpecs[i].Parents[i2]).(*ast.GenDecl) == file.Decls[i3] {
-						file.Decls[i3] = file.Decls[len(file.Decls)-1]
-						file.Decls = file.Decls[:len(file.Decls)-1]
-						break
-					}
+	importSpecs, err := FindAllContext[*ast.ImportSpec](ctx, file, nil)
+	if err != nil {
+		return err
+	}
+	for _, match := range importSpecs {
+		requiredImports[strings.ReplaceAll(match.Node.Path.Value, "\"", "")] = true
+		if genDecl, ok := match.Path.Parent().(*ast.GenDecl); ok {
+			for i := range file.Decls {
+				if file.Decls[i] == genDecl {
+					file.Decls[i] = file.Decls[len(file.Decls)-1]
+					file.Decls = file.Decls[:len(file.Decls)-1]
+					break
 				}
 			}
 		}
@@ -98,6 +98,7 @@ func AddMissingImports(file *ast.File, imports []string) {
 		Tok:   token.IMPORT,
 		Specs: specs,
 	}}, file.Decls...)
+	return nil
 }
 
 func ReplaceImports(file *ast.File, imports []string) {
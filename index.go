@@ -0,0 +1,164 @@
+package AstUtils
+
+import (
+	"go/ast"
+	"reflect"
+)
+
+// Index buckets the nodes of a tree by their type, their names and the tag keys of fields, so repeated lookups don't
+// need to traverse the tree again. It also records the path of every node, which gives access to the parents. The
+// index is built once on creation. After the tree got edited, call Invalidate, the index is rebuilt on the next lookup.
+// An Index isn't safe for concurrent use.
+type Index struct {
+	root   ast.Node
+	opts   []SearchOption
+	stale  bool
+	order  []reflect.Type
+	byType map[reflect.Type][]Match[ast.Node]
+	byName map[string][]Match[ast.Node]
+	byTag  map[string][]Match[*ast.Field]
+	paths  map[ast.Node]Path
+}
+
+// NewIndex Builds an index of root and all nodes below it.
+func NewIndex(root ast.Node, opts ...SearchOption) *Index {
+	idx := &Index{
+		root: root,
+		opts: opts,
+	}
+	idx.build()
+	return idx
+}
+
+// Invalidate Marks the index as outdated, e.g. after nodes got edited. The index is rebuilt on the next lookup.
+func (idx *Index) Invalidate() {
+	idx.stale = true
+}
+
+func (idx *Index) build() {
+	idx.stale = false
+	idx.order = nil
+	idx.byType = map[reflect.Type][]Match[ast.Node]{}
+	idx.byName = map[string][]Match[ast.Node]{}
+	idx.byTag = map[string][]Match[*ast.Field]{}
+	idx.paths = map[ast.Node]Path{}
+	for node, path := range WalkShared(idx.root, idx.opts...) {
+		path = path.Clone()
+		match := Match[ast.Node]{
+			Node: node,
+			Path: path,
+		}
+		t := reflect.TypeOf(node)
+		if _, ok := idx.byType[t]; !ok {
+			idx.order = append(idx.order, t)
+		}
+		idx.byType[t] = append(idx.byType[t], match)
+		idx.paths[node] = path
+		for _, name := range indexNames(node) {
+			idx.byName[name] = append(idx.byName[name], match)
+		}
+		if field, ok := node.(*ast.Field); ok && field.Tag != nil {
+			for key := range ExtractTagsByKey(field.Tag) {
+				idx.byTag[key] = append(idx.byTag[key], Match[*ast.Field]{
+					Node: field,
+					Path: path,
+				})
+			}
+		}
+	}
+}
+
+// indexNames Returns the names a node is found by. Fields and value specs are found by each of their names.
+func indexNames(node ast.Node) []string {
+	var idents []*ast.Ident
+	switch n := node.(type) {
+	case *ast.Field:
+		idents = n.Names
+	case *ast.ValueSpec:
+		idents = n.Names
+	default:
+		if name := NodeName(node); name != "" {
+			return []string{name}
+		}
+	}
+	names := make([]string, len(idents))
+	for i, ident := range idents {
+		names[i] = ident.Name
+	}
+	return names
+}
+
+func (idx *Index) refresh() {
+	if idx.stale {
+		idx.build()
+	}
+}
+
+// ByName Returns all nodes with the given name in traversal order, see NodeName. Fields and value specs are found by
+// each of their names.
+func (idx *Index) ByName(name string) []Match[ast.Node] {
+	idx.refresh()
+	return idx.byName[name]
+}
+
+// ByTagKey Returns all fields whose tag contains key, in traversal order.
+func (idx *Index) ByTagKey(key string) []Match[*ast.Field] {
+	idx.refresh()
+	return idx.byTag[key]
+}
+
+// Path Returns the path of node and whether the node is part of the index.
+func (idx *Index) Path(node ast.Node) (Path, bool) {
+	idx.refresh()
+	path, ok := idx.paths[node]
+	return path, ok
+}
+
+// Parent Returns the direct parent of node, or nil if node is the root or not part of the index.
+func (idx *Index) Parent(node ast.Node) ast.Node {
+	path, _ := idx.Path(node)
+	return path.Parent()
+}
+
+// IndexedOfType Returns all nodes of type T from the index. For concrete node types the nodes are returned in
+// traversal order, for interfaces like ast.Expr they are grouped by their concrete type.
+func IndexedOfType[T ast.Node](idx *Index) []Match[T] {
+	idx.refresh()
+	t := reflect.TypeFor[T]()
+	var matches []Match[T]
+	if t.Kind() != reflect.Interface {
+		for _, match := range idx.byType[t] {
+			matches = append(matches, Match[T]{
+				Node: match.Node.(T),
+				Path: match.Path,
+			})
+		}
+		return matches
+	}
+	for _, nodeType := range idx.order {
+		if !nodeType.Implements(t) {
+			continue
+		}
+		for _, match := range idx.byType[nodeType] {
+			matches = append(matches, Match[T]{
+				Node: match.Node.(T),
+				Path: match.Path,
+			})
+		}
+	}
+	return matches
+}
+
+// IndexedByName Returns all nodes of type T with the given name from the index, e.g. all *ast.TypeSpec named User.
+func IndexedByName[T ast.Node](idx *Index, name string) []Match[T] {
+	var matches []Match[T]
+	for _, match := range idx.ByName(name) {
+		if node, ok := match.Node.(T); ok {
+			matches = append(matches, Match[T]{
+				Node: node,
+				Path: match.Path,
+			})
+		}
+	}
+	return matches
+}
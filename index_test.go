@@ -0,0 +1,212 @@
+package AstUtils
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+const indexSrc = `package p
+
+type User struct {
+	Name string ` + "`json:\"name\" db:\"name\"`" + `
+	Age  int    ` + "`json:\"age\"`" + `
+}
+
+var a, b = 1, 2
+
+func Name() string { return "" }
+`
+
+func parseIndexSrc(t *testing.T) *ast.File {
+	t.Helper()
+	file, err := parser.ParseFile(token.NewFileSet(), "index.go", indexSrc, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// matchNames Returns the names of all matched nodes, see indexNames.
+func matchNames[T ast.Node](matches []Match[T]) []string {
+	var names []string
+	for _, match := range matches {
+		names = append(names, indexNames(match.Node)...)
+	}
+	return names
+}
+
+func TestNewIndex(t *testing.T) {
+	file := parseIndexSrc(t)
+	idx := NewIndex(file)
+
+	tests := []struct {
+		name string
+		// want holds the types of the found nodes in traversal order
+		want []string
+	}{
+		{name: "User", want: []string{"*ast.TypeSpec", "*ast.Ident"}},
+		{name: "Name", want: []string{"*ast.Field", "*ast.Ident", "*ast.FuncDecl", "*ast.Ident"}},
+		{name: "b", want: []string{"*ast.ValueSpec", "*ast.Ident"}},
+		{name: "missing"},
+	}
+	for _, test := range tests {
+		var got []string
+		for _, match := range idx.ByName(test.name) {
+			got = append(got, fmt.Sprintf("%T", match.Node))
+			if node, _, _ := ResolvePath(file, match.Path.String()); node != match.Node {
+				t.Errorf("%s: path %s doesn't lead to the %T", test.name, match.Path, match.Node)
+			}
+		}
+		if !slices.Equal(got, test.want) {
+			t.Errorf("%s: found %v, want %v", test.name, got, test.want)
+		}
+	}
+	if got := idx.ByName("Name")[0].Path.String(); got != "Decls[0].Specs[0].Type.Fields.List[0]" {
+		t.Errorf("path of the field is %s", got)
+	}
+
+	if specs := IndexedByName[*ast.TypeSpec](idx, "User"); len(specs) != 1 || specs[0].Node.Name.Name != "User" {
+		t.Errorf("IndexedByName found %d type specs named User", len(specs))
+	}
+	if decls := IndexedByName[*ast.FuncDecl](idx, "User"); len(decls) != 0 {
+		t.Errorf("IndexedByName found %d functions named User", len(decls))
+	}
+}
+
+func TestIndexByTagKey(t *testing.T) {
+	idx := NewIndex(parseIndexSrc(t))
+	tests := []struct {
+		key  string
+		want []string
+	}{
+		{key: "json", want: []string{"Name", "Age"}},
+		{key: "db", want: []string{"Name"}},
+		{key: "xml", want: nil},
+	}
+	for _, test := range tests {
+		if got := matchNames(idx.ByTagKey(test.key)); !slices.Equal(got, test.want) {
+			t.Errorf("key %s: found fields %v, want %v", test.key, got, test.want)
+		}
+	}
+}
+
+func TestIndexedOfType(t *testing.T) {
+	idx := NewIndex(parseIndexSrc(t))
+
+	if fields := IndexedOfType[*ast.Field](idx); len(fields) != 3 {
+		t.Errorf("found %d fields, want the 2 struct fields and the result of Name", len(fields))
+	}
+
+	specs := IndexedOfType[ast.Spec](idx)
+	if len(specs) != 2 {
+		t.Fatalf("found %d specs, want 2", len(specs))
+	}
+	if _, ok := specs[0].Node.(*ast.TypeSpec); !ok {
+		t.Errorf("first spec is %T, want *ast.TypeSpec", specs[0].Node)
+	}
+	if _, ok := specs[1].Node.(*ast.ValueSpec); !ok {
+		t.Errorf("second spec is %T, want *ast.ValueSpec", specs[1].Node)
+	}
+
+	decls := IndexedOfType[ast.Decl](idx)
+	if len(decls) != 3 {
+		t.Errorf("found %d declarations, want 3", len(decls))
+	}
+	for _, decl := range decls {
+		if len(decl.Path) != 1 || decl.Path[0].Field != "Decls" {
+			t.Errorf("declaration %T has path %s", decl.Node, decl.Path)
+		}
+	}
+
+	// Interfaces group the nodes by their concrete type, in the order the types were found.
+	var idents []int
+	for i, expr := range IndexedOfType[ast.Expr](idx) {
+		if _, ok := expr.Node.(*ast.Ident); ok {
+			idents = append(idents, i)
+		}
+	}
+	if len(idents) != len(IndexedOfType[*ast.Ident](idx)) {
+		t.Errorf("found %d identifiers as expressions, want %d", len(idents), len(IndexedOfType[*ast.Ident](idx)))
+	}
+	if len(idents) > 0 && idents[len(idents)-1]-idents[0] != len(idents)-1 {
+		t.Errorf("identifiers aren't grouped, found at %v", idents)
+	}
+}
+
+func TestIndexParent(t *testing.T) {
+	file := parseIndexSrc(t)
+	idx := NewIndex(file)
+
+	field := IndexedByName[*ast.Field](idx, "Age")[0].Node
+	if list, ok := idx.Parent(field).(*ast.FieldList); !ok || list.List[1] != field {
+		t.Errorf("parent of the field Age is %T, want its field list", idx.Parent(field))
+	}
+	spec := IndexedByName[*ast.TypeSpec](idx, "User")[0].Node
+	if idx.Parent(spec) != file.Decls[0] {
+		t.Errorf("parent of the type spec is %T, want its declaration", idx.Parent(spec))
+	}
+	if parent := idx.Parent(file); parent != nil {
+		t.Errorf("parent of the root is %T, want nil", parent)
+	}
+	if parent := idx.Parent(ast.NewIdent("other")); parent != nil {
+		t.Errorf("parent of a node outside of the index is %T, want nil", parent)
+	}
+	if _, ok := idx.Path(ast.NewIdent("other")); ok {
+		t.Error("node outside of the index reported as indexed")
+	}
+}
+
+func TestIndexInvalidate(t *testing.T) {
+	file := parseIndexSrc(t)
+	idx := NewIndex(file)
+
+	found := IndexedByName[*ast.Field](idx, "Age")
+	if len(found) != 1 {
+		t.Fatalf("found %d fields named Age, want 1", len(found))
+	}
+	if err := ReplaceNode(found[0].Path, &ast.Field{
+		Names: []*ast.Ident{ast.NewIdent("Email")},
+		Type:  ast.NewIdent("string"),
+		Tag:   &ast.BasicLit{Kind: token.STRING, Value: "`xml:\"email\"`"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without Invalidate the index still describes the tree before the edit.
+	if len(idx.ByName("Age")) == 0 || len(idx.ByName("Email")) != 0 {
+		t.Error("index rebuilt without Invalidate")
+	}
+
+	idx.Invalidate()
+	if got := idx.ByName("Age"); len(got) != 0 {
+		t.Errorf("found %d nodes named Age after the rebuild", len(got))
+	}
+	email := IndexedByName[*ast.Field](idx, "Email")
+	if len(email) != 1 {
+		t.Fatalf("found %d fields named Email after the rebuild, want 1", len(email))
+	}
+	if got := email[0].Path.String(); got != "Decls[0].Specs[0].Type.Fields.List[1]" {
+		t.Errorf("path of the new field is %s", got)
+	}
+	if got := matchNames(idx.ByTagKey("json")); len(got) != 1 || got[0] != "Name" {
+		t.Errorf("fields with a json tag after the rebuild: %v", got)
+	}
+	if got := matchNames(idx.ByTagKey("xml")); len(got) != 1 || got[0] != "Email" {
+		t.Errorf("fields with a xml tag after the rebuild: %v", got)
+	}
+	if _, ok := idx.Parent(email[0].Node).(*ast.FieldList); !ok {
+		t.Errorf("parent of the new field is %T", idx.Parent(email[0].Node))
+	}
+
+	// Lookups after the rebuild don't rebuild again, until the next Invalidate.
+	before := idx.byType
+	IndexedOfType[*ast.Field](idx)
+	if reflect.ValueOf(before).Pointer() != reflect.ValueOf(idx.byType).Pointer() {
+		t.Error("index rebuilt without Invalidate")
+	}
+}
@@ -0,0 +1,36 @@
+package AstUtils
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// Location holds the position of a node inside its file and, if the source of the file is known, the source text
+// of the node.
+type Location struct {
+	Start  token.Position
+	End    token.Position
+	Source string
+}
+
+// Locate Returns the location of node. fset must be the FileSet the node was parsed with. src is the source of the
+// file the node was parsed from, it may be nil if the source text isn't needed. Source stays empty if src is nil or
+// doesn't fit the positions of the node.
+func Locate(node ast.Node, fset *token.FileSet, src []byte) *Location {
+	location := &Location{
+		Start: fset.Position(node.Pos()),
+		End:   fset.Position(node.End()),
+	}
+	if src != nil && location.Start.IsValid() && location.End.IsValid() && location.Start.Filename == location.End.Filename &&
+		location.Start.Offset <= location.End.Offset && location.End.Offset <= len(src) {
+		location.Source = string(src[location.Start.Offset:location.End.Offset])
+	}
+	return location
+}
+
+// AddLocations Sets the Location of all matches, see Locate.
+func AddLocations(matches []*FoundNodes, fset *token.FileSet, src []byte) {
+	for _, match := range matches {
+		match.Location = Locate(*match.Node, fset, src)
+	}
+}
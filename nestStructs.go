@@ -0,0 +1,344 @@
+package AstUtils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"reflect"
+	"slices"
+)
+
+// InlinedStruct describes a named struct type inlined by NestStruct. Field is the field whose type now holds the
+// struct. Comments holds the comments that were inside the struct type. They are removed from the file, as their
+// positions belong to the removed declaration.
+type InlinedStruct struct {
+	Name     string
+	Struct   *ast.StructType
+	Field    *ast.Field
+	Comments []*ast.CommentGroup
+}
+
+// nestCandidate is a named struct type, that may be inlined at its only use.
+type nestCandidate struct {
+	file    *ast.File
+	decl    *ast.GenDecl
+	spec    *ast.TypeSpec
+	methods bool
+	uses    []Match[*ast.Ident]
+}
+
+// NestOptions configures NestStructWithOptions. The zero value inlines structs the way NestStruct does.
+type NestOptions struct {
+	// Exported inlines exported types as well. References from other packages aren't known, so they are only inlined
+	// if they are named explicitly otherwise.
+	Exported bool
+	// WholePackage states that the file passed to NestStructWithOptions is the only file of its package. References
+	// from other files of the package aren't known, so without it types are only inlined if they are named. The
+	// methods of Package count the references in all files of the package and don't need it.
+	WholePackage bool
+}
+
+// NestStruct Inlines named struct types, that are referenced exactly once inside the file, at the place they are
+// used, and removes their declaration. The reference is replaced by the struct type itself, so *T becomes
+// *struct{...} and T becomes struct{...}, keeping pointer and value semantics. Types are only inlined into the types of
+// fields, parameters and results, possibly nested inside pointers, slices, arrays, maps and channels, but not into
+// embedded fields, which would lose their name. References from other packages aren't known, so exported types are
+// skipped unless they are named or NestOptions.Exported is set. With that option this is the inverse of UnnestStruct,
+// which extracts exported types.
+//
+// If a name is given, only the named type is inlined and an error is returned, if it can't be inlined because it has
+// methods, type parameters, is embedded or isn't referenced exactly once. Otherwise all types that can be inlined
+// are, including types that only become single use by inlining another one. As other files of the package may refer
+// to the types, that's only done for a file that is the whole package, see NestOptions.WholePackage, and an error is
+// returned otherwise. Use Package.NestStruct for packages of several files.
+func NestStruct(structName *string, file *ast.File) ([]*InlinedStruct, error) {
+	return NestStructWithOptionsContext(context.Background(), structName, file, NestOptions{})
+}
+
+// NestStructContext Works like NestStruct, but stops once ctx is done. Types inlined until then stay inlined. Returns
+// ctx.Err() in that case.
+func NestStructContext(ctx context.Context, structName *string, file *ast.File) ([]*InlinedStruct, error) {
+	return NestStructWithOptionsContext(ctx, structName, file, NestOptions{})
+}
+
+// NestStructWithOptions Works like NestStruct, but inlines the types as configured by options.
+func NestStructWithOptions(structName *string, file *ast.File, options NestOptions) ([]*InlinedStruct, error) {
+	return NestStructWithOptionsContext(context.Background(), structName, file, options)
+}
+
+// NestStructWithOptionsContext Works like NestStructWithOptions, but stops once ctx is done. Types inlined until then
+// stay inlined. Returns ctx.Err() in that case.
+func NestStructWithOptionsContext(ctx context.Context, structName *string, file *ast.File, options NestOptions) ([]*InlinedStruct, error) {
+	return nestStructs(ctx, structName, []*ast.File{file}, options)
+}
+
+// NestStruct Works like the function NestStruct, but counts the references across all files of the package. A type
+// may be inlined into another file than the one declaring it.
+func (p *Package) NestStruct(structName *string) ([]*InlinedStruct, error) {
+	return p.NestStructWithOptionsContext(context.Background(), structName, NestOptions{})
+}
+
+// NestStructContext Works like Package.NestStruct, but stops once ctx is done. Types inlined until then stay inlined.
+// Returns ctx.Err() in that case.
+func (p *Package) NestStructContext(ctx context.Context, structName *string) ([]*InlinedStruct, error) {
+	return p.NestStructWithOptionsContext(ctx, structName, NestOptions{})
+}
+
+// NestStructWithOptions Works like Package.NestStruct, but inlines the types as configured by options.
+func (p *Package) NestStructWithOptions(structName *string, options NestOptions) ([]*InlinedStruct, error) {
+	return p.NestStructWithOptionsContext(context.Background(), structName, options)
+}
+
+// NestStructWithOptionsContext Works like Package.NestStructWithOptions, but stops once ctx is done. Types inlined
+// until then stay inlined. Returns ctx.Err() in that case.
+func (p *Package) NestStructWithOptionsContext(ctx context.Context, structName *string, options NestOptions) ([]*InlinedStruct, error) {
+	files := make([]*ast.File, len(p.Files))
+	for i, file := range p.Files {
+		files[i] = file.File
+	}
+	options.WholePackage = true
+	return nestStructs(ctx, structName, files, options)
+}
+
+func nestStructs(ctx context.Context, structName *string, files []*ast.File, options NestOptions) ([]*InlinedStruct, error) {
+	if structName == nil && !options.WholePackage {
+		return nil, errors.New("other files of the package may refer to the types, name a type or use Package.NestStruct")
+	}
+	var inlined []*InlinedStruct
+	for {
+		candidates, err := nestCandidates(ctx, files)
+		if err != nil {
+			return inlined, err
+		}
+		var next *nestCandidate
+		for _, candidate := range candidates {
+			if structName != nil && candidate.spec.Name.Name != *structName {
+				continue
+			}
+			if structName == nil && !options.Exported && candidate.spec.Name.IsExported() {
+				continue
+			}
+			err := candidate.check()
+			if err == nil {
+				next = candidate
+				break
+			}
+			if structName != nil {
+				return inlined, err
+			}
+		}
+		if next == nil {
+			if structName != nil && len(inlined) == 0 {
+				return nil, fmt.Errorf("no struct type %s declared", *structName)
+			}
+			return inlined, nil
+		}
+		// Every inlining changes the references, so the candidates are collected again afterwards
+		// the struct is copied to the position of the reference without its comments, like the nodes produced by a
+		// rewrite, so it's printed there
+		use := next.uses[0]
+		moved, err := substitute(reflect.ValueOf(next.spec.Type), nil, use.Node.Pos())
+		if err != nil {
+			return inlined, err
+		}
+		structType := moved.Interface().(*ast.StructType)
+		if err := ReplaceNode(use.Path, structType); err != nil {
+			return inlined, err
+		}
+		inlined = append(inlined, &InlinedStruct{
+			Name:     next.spec.Name.Name,
+			Struct:   structType,
+			Field:    use.Path.EnclosingField(),
+			Comments: next.remove(),
+		})
+		if structName != nil {
+			return inlined, nil
+		}
+	}
+}
+
+// nestCandidates Returns all named struct types declared at the top level of files, in the order they are declared,
+// together with their references.
+func nestCandidates(ctx context.Context, files []*ast.File) ([]*nestCandidate, error) {
+	var candidates []*nestCandidate
+	byName := map[string]*nestCandidate{}
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				typeSpec, ok := spec.(*ast.TypeSpec)
+				if !ok || typeSpec.Assign.IsValid() {
+					continue
+				}
+				if _, ok := typeSpec.Type.(*ast.StructType); !ok {
+					continue
+				}
+				candidate := &nestCandidate{
+					file: file,
+					decl: genDecl,
+					spec: typeSpec,
+				}
+				candidates = append(candidates, candidate)
+				byName[typeSpec.Name.Name] = candidate
+			}
+		}
+	}
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			if funcDecl, ok := decl.(*ast.FuncDecl); ok && funcDecl.Recv != nil && len(funcDecl.Recv.List) > 0 {
+				if candidate, ok := byName[receiverName(funcDecl.Recv.List[0].Type)]; ok {
+					candidate.methods = true
+				}
+			}
+		}
+		uses, err := FindAllContext(ctx, file, func(ident *ast.Ident, path Path) bool {
+			return byName[ident.Name] != nil && !isDeclaringIdent(path)
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, use := range uses {
+			candidate := byName[use.Node.Name]
+			candidate.uses = append(candidate.uses, use)
+		}
+	}
+	return candidates, nil
+}
+
+// receiverName Returns the name of the type of a method receiver, e.g. T for *T or T[K].
+func receiverName(expr ast.Expr) string {
+	switch e := ast.Unparen(expr).(type) {
+	case *ast.StarExpr:
+		return receiverName(e.X)
+	case *ast.IndexExpr:
+		return receiverName(e.X)
+	case *ast.IndexListExpr:
+		return receiverName(e.X)
+	case *ast.Ident:
+		return e.Name
+	}
+	return ""
+}
+
+// isDeclaringIdent Reports whether the identifier at path names something instead of referring to a type, like the
+// name of a type spec, a field, a method or the key of a struct literal.
+func isDeclaringIdent(path Path) bool {
+	if len(path) == 0 {
+		return false
+	}
+	step := path[len(path)-1]
+	switch step.Parent.(type) {
+	case *ast.TypeSpec, *ast.FuncDecl:
+		return step.Field == "Name"
+	case *ast.Field:
+		return step.Field == "Names"
+	case *ast.SelectorExpr:
+		return step.Field == "Sel"
+	case *ast.KeyValueExpr:
+		_, ok := path[:len(path)-1].Parent().(*ast.CompositeLit)
+		return ok && step.Field == "Key"
+	case *ast.LabeledStmt, *ast.BranchStmt:
+		return true
+	}
+	return false
+}
+
+// check Returns an error, if the candidate can't be inlined.
+func (c *nestCandidate) check() error {
+	name := c.spec.Name.Name
+	if c.spec.TypeParams != nil {
+		return fmt.Errorf("type %s has type parameters", name)
+	}
+	if c.methods {
+		return fmt.Errorf("type %s has methods", name)
+	}
+	if len(c.uses) != 1 {
+		return fmt.Errorf("type %s is referenced %d times", name, len(c.uses))
+	}
+	use := c.uses[0]
+	if slices.ContainsFunc(use.Path, func(step PathStep) bool {
+		return step.Parent == c.spec
+	}) {
+		return fmt.Errorf("type %s refers to itself", name)
+	}
+	if !isFieldType(use.Path) {
+		return fmt.Errorf("type %s is used outside of a field type at %s", name, use.Path)
+	}
+	if isEmbedded(use.Path) {
+		return fmt.Errorf("type %s is embedded at %s", name, use.Path)
+	}
+	return nil
+}
+
+// isEmbedded Reports whether the type at path is part of the type of an embedded field. Inlining a struct there would
+// lose the name of the promoted field and doesn't parse.
+func isEmbedded(path Path) bool {
+	for i := len(path) - 1; i >= 0; i-- {
+		if field, ok := path[i].Parent.(*ast.Field); ok {
+			if len(field.Names) > 0 || i < 2 {
+				return false
+			}
+			switch path[i-2].Parent.(type) {
+			case *ast.StructType, *ast.InterfaceType:
+				return true
+			}
+			return false
+		}
+	}
+	return false
+}
+
+// isFieldType Reports whether the type at path is the type of a field, or part of it, like the element type of a
+// slice or a type argument.
+func isFieldType(path Path) bool {
+	for i := len(path) - 1; i >= 0; i-- {
+		switch path[i].Parent.(type) {
+		case *ast.Field:
+			return path[i].Field == "Type"
+		case *ast.ArrayType:
+			if path[i].Field != "Elt" {
+				return false
+			}
+		case *ast.IndexExpr, *ast.IndexListExpr:
+			if path[i].Field == "X" {
+				return false
+			}
+		case *ast.StarExpr, *ast.MapType, *ast.ChanType, *ast.ParenExpr, *ast.Ellipsis:
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// remove Removes the declaration of the candidate from its file, together with its doc comments and the comments
+// inside the struct type. Returns the comments inside the struct type.
+func (c *nestCandidate) remove() []*ast.CommentGroup {
+	structType := c.spec.Type.(*ast.StructType)
+	var inner []*ast.CommentGroup
+	for _, group := range c.file.Comments {
+		if group.Pos() >= structType.Pos() && group.End() <= structType.End() {
+			inner = append(inner, group)
+		}
+	}
+	dropped := append([]*ast.CommentGroup{c.spec.Doc, c.spec.Comment}, inner...)
+	if len(c.decl.Specs) == 1 {
+		c.file.Decls = slices.DeleteFunc(c.file.Decls, func(decl ast.Decl) bool {
+			return decl == c.decl
+		})
+		dropped = append(dropped, c.decl.Doc)
+	} else {
+		c.decl.Specs = slices.DeleteFunc(c.decl.Specs, func(spec ast.Spec) bool {
+			return spec == c.spec
+		})
+	}
+	c.file.Comments = slices.DeleteFunc(c.file.Comments, func(group *ast.CommentGroup) bool {
+		return slices.Contains(dropped, group)
+	})
+	return inner
+}
@@ -0,0 +1,198 @@
+package AstUtils
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNestStructDropsInnerComments(t *testing.T) {
+	src := `package p
+
+// address doc
+type address struct {
+	// Street doc
+	Street string // trailing
+	/* free */
+	Zip int
+}
+
+type User struct {
+	// name
+	Name string
+	Home address
+}
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "test.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inlined, err := NestStructWithOptions(nil, file, NestOptions{WholePackage: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inlined) != 1 {
+		t.Fatalf("inlined %d structs, want 1", len(inlined))
+	}
+	if len(inlined[0].Comments) != 3 {
+		t.Errorf("got %d comments of the struct, want 3", len(inlined[0].Comments))
+	}
+	if len(file.Comments) != 1 || file.Comments[0].Text() != "name\n" {
+		t.Errorf("file keeps comments of the inlined struct: %d groups", len(file.Comments))
+	}
+	ast.Inspect(inlined[0].Struct, func(node ast.Node) bool {
+		if field, ok := node.(*ast.Field); ok && (field.Doc != nil || field.Comment != nil) {
+			t.Errorf("field %s keeps its comments", field.Names[0].Name)
+		}
+		return true
+	})
+}
+
+func TestNestStructExported(t *testing.T) {
+	src := `package p
+
+type Address struct{ Street string }
+
+type geo struct{ Lat float64 }
+
+type User struct {
+	Home Address
+	Geo  geo
+}
+`
+	name := "Address"
+	tests := []struct {
+		name       string
+		structName *string
+		options    NestOptions
+		want       []string
+	}{
+		{name: "unexported only", options: NestOptions{WholePackage: true}, want: []string{"geo"}},
+		{name: "named", structName: &name, want: []string{"Address"}},
+		{name: "option", options: NestOptions{Exported: true, WholePackage: true}, want: []string{"Address", "geo"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			file, err := parser.ParseFile(token.NewFileSet(), "test.go", src, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			inlined, err := NestStructWithOptions(test.structName, file, test.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, s := range inlined {
+				got = append(got, s.Name)
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("inlined %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestNestStruct(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		// structName is inlined explicitly, if set
+		structName string
+		want       string
+		wantErr    bool
+	}{
+		{
+			name: "value",
+			src:  "type s struct{ X int }\n\ntype T struct{ S s }",
+			want: "type T struct{ S struct{ X int } }",
+		},
+		{
+			name: "pointer",
+			src:  "type s struct{ X int }\n\ntype T struct{ S *s }",
+			want: "type T struct{ S *struct{ X int } }",
+		},
+		{
+			name: "slice of pointers",
+			src:  "type s struct{ X int }\n\ntype T struct{ S []*s }",
+			want: "type T struct{ S []*struct{ X int } }",
+		},
+		{
+			name: "parameter",
+			src:  "type s struct{ X int }\n\nfunc f(*s) {}",
+			want: "func f(*struct{ X int }) {}",
+		},
+		{
+			name: "several fields",
+			src:  "type s struct {\n\tX int\n\tY string\n}\n\nfunc f(a, b int, c []s) {}",
+			want: "func f(a, b int, c []struct {\n\tX int\n\tY string\n}) {}",
+		},
+		{name: "methods", src: "type s struct{ X int }\n\nfunc (*s) m() {}\n\ntype T struct{ S s }", structName: "s", wantErr: true},
+		{name: "several references", src: "type s struct{ X int }\n\ntype T struct {\n\tA s\n\tB *s\n}", structName: "s", wantErr: true},
+		{name: "type parameters", src: "type s[V any] struct{ X V }\n\ntype T struct{ S s[int] }", structName: "s", wantErr: true},
+		{name: "itself", src: "type s struct{ Next *s }", structName: "s", wantErr: true},
+		{name: "embedded", src: "type s struct{ X int }\n\ntype T struct{ s }", structName: "s", wantErr: true},
+		{name: "embedded pointer", src: "type s struct{ X int }\n\ntype T struct{ *s }", structName: "s", wantErr: true},
+		{name: "outside of a field", src: "type s struct{ X int }\n\nvar v = s{}", structName: "s", wantErr: true},
+		{name: "embedded skipped", src: "type s struct{ X int }\n\ntype T struct{ *s }", want: "type T struct{ *s }"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var structName *string
+			if test.structName != "" {
+				structName = &test.structName
+			}
+			result, err := unnest(t, "package p\n\n"+test.src+"\n", func(file *ast.File) error {
+				_, err := NestStructWithOptions(structName, file, NestOptions{WholePackage: true})
+				return err
+			})
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("inlined, want an error:\n%s", result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(result, test.want) {
+				t.Errorf("result doesn't contain %q:\n%s", test.want, result)
+			}
+		})
+	}
+}
+
+func TestNestStructPackage(t *testing.T) {
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for name, src := range map[string]string{
+		"a.go": "package p\n\ntype shared struct{ X int }\n\ntype local struct{ Y int }\n\ntype A struct{ S shared }\n",
+		"b.go": "package p\n\ntype B struct {\n\tS shared\n\tL *local\n}\n",
+	} {
+		file, err := parser.ParseFile(fset, name, src, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, file)
+	}
+	if _, err := NestStruct(nil, files[0]); err == nil {
+		t.Error("NestStruct inlined the types of a single file of the package")
+	}
+	pkg, err := NewPackage(fset, files...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inlined, err := pkg.NestStruct(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inlined) != 1 || inlined[0].Name != "local" {
+		t.Fatalf("inlined %d types, want local only", len(inlined))
+	}
+	if _, ok := inlined[0].Field.Type.(*ast.StarExpr); !ok || inlined[0].Field.Names[0].Name != "L" {
+		t.Errorf("local inlined into the wrong field")
+	}
+}
@@ -0,0 +1,27 @@
+package AstUtils
+
+// SearchOption configures a traversal. Options can be passed to SearchNodes, SearchTree, Walk, FindAll, FindFirst
+// and VisitNodes.
+type SearchOption func(config *searchConfig)
+
+type searchConfig struct {
+	allComments bool
+}
+
+// IncludeComments Visits every comment group of a file, including the ones that aren't attached to a node as Doc or
+// Comment. Without this option, the same nodes ast.Inspect visits are visited. Comment groups that are not attached
+// to a node are visited after the declarations of the file, through the Comments field. Every comment group is visited
+// once.
+func IncludeComments() SearchOption {
+	return func(config *searchConfig) {
+		config.allComments = true
+	}
+}
+
+func newSearchConfig(opts []SearchOption) searchConfig {
+	var config searchConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+	return config
+}
@@ -0,0 +1,231 @@
+package AstUtils
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Package holds all parsed files of a package. All files share the same FileSet, so positions of nodes from different
+// files can be resolved and compared. Path is the import path of the package. It's set by LoadPackage, if it can be
+// determined, and used by TypeCheck.
+type Package struct {
+	Name  string
+	Path  string
+	Fset  *token.FileSet
+	Files []*PackageFile
+}
+
+// PackageFile is a single parsed file of a Package. Src holds the source of the file, if the package was parsed by
+// LoadPackage or ParsePackage.
+type PackageFile struct {
+	Filename string
+	File     *ast.File
+	Src      []byte
+}
+
+// FileMatch is a match found by a search across a Package. Besides the node and its ancestry, it holds the file the
+// node was found in and the position of the node. The Location of the match is always set, its Source only if the
+// source of the file is known.
+type FileMatch struct {
+	*FoundNodes
+	File     *ast.File
+	Filename string
+	Position token.Position
+}
+
+// LoadPackage Parses all go files of dir, including comments, into a Package. Files excluded by build constraints for
+// the current platform and test files are skipped, unless includeTests is set. Test files of the external test package,
+// package name_test, are always skipped, as they form a package of their own. They can be parsed with ParsePackage.
+// Returns an error if the files belong to different packages.
+func LoadPackage(dir string, includeTests bool) (*Package, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var filenames []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") {
+			continue
+		}
+		if !includeTests && strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		if match, err := build.Default.MatchFile(dir, name); err != nil {
+			return nil, err
+		} else if !match {
+			continue
+		}
+		if strings.HasSuffix(name, "_test.go") {
+			if external, err := isExternalTest(filepath.Join(dir, name)); err != nil {
+				return nil, err
+			} else if external {
+				continue
+			}
+		}
+		filenames = append(filenames, filepath.Join(dir, name))
+	}
+	pkg, err := ParsePackage(token.NewFileSet(), filenames...)
+	if err != nil {
+		return nil, err
+	}
+	pkg.Path = importPath(dir)
+	return pkg, nil
+}
+
+// isExternalTest Reports whether the file belongs to an external test package, whose name ends with _test.
+func isExternalTest(filename string) (bool, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), filename, nil, parser.PackageClauseOnly)
+	if err != nil {
+		return false, err
+	}
+	return strings.HasSuffix(file.Name.Name, "_test"), nil
+}
+
+// importPath Returns the import path of the package in dir. Packages inside GOROOT or GOPATH are resolved by go/build,
+// packages of modules using the module path of the closest go.mod. Returns an empty string if neither is found.
+func importPath(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	if pkg, err := build.ImportDir(dir, build.FindOnly); err == nil && pkg.ImportPath != "." {
+		return pkg.ImportPath
+	}
+	for moduleDir := dir; ; {
+		if data, err := os.ReadFile(filepath.Join(moduleDir, "go.mod")); err == nil {
+			modulePath := modulePathOf(data)
+			rel, err := filepath.Rel(moduleDir, dir)
+			if modulePath == "" || err != nil {
+				return ""
+			}
+			if rel == "." {
+				return modulePath
+			}
+			return modulePath + "/" + filepath.ToSlash(rel)
+		}
+		parent := filepath.Dir(moduleDir)
+		if parent == moduleDir {
+			return ""
+		}
+		moduleDir = parent
+	}
+}
+
+// modulePathOf Returns the module path declared by the content of a go.mod file, or an empty string.
+func modulePathOf(goMod []byte) string {
+	for _, line := range strings.Split(string(goMod), "\n") {
+		line, _, _ = strings.Cut(line, "//")
+		fields := strings.Fields(line)
+		if len(fields) != 2 || fields[0] != "module" {
+			continue
+		}
+		if path, err := strconv.Unquote(fields[1]); err == nil {
+			return path
+		}
+		return fields[1]
+	}
+	return ""
+}
+
+// ParsePackage Parses the given files, including comments, into a Package using fset.
+func ParsePackage(fset *token.FileSet, filenames ...string) (*Package, error) {
+	var files []*ast.File
+	sources := map[*ast.File][]byte{}
+	for _, filename := range filenames {
+		src, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+		sources[file] = src
+	}
+	pkg, err := NewPackage(fset, files...)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range pkg.Files {
+		file.Src = sources[file.File]
+	}
+	return pkg, nil
+}
+
+// NewPackage Creates a Package from already parsed files. fset must be the FileSet the files were parsed with. The
+// files are sorted by filename. Returns an error if the files belong to different packages.
+func NewPackage(fset *token.FileSet, files ...*ast.File) (*Package, error) {
+	pkg := &Package{
+		Fset: fset,
+	}
+	for _, file := range files {
+		if pkg.Name == "" {
+			pkg.Name = file.Name.Name
+		} else if pkg.Name != file.Name.Name {
+			return nil, fmt.Errorf("found packages %s and %s", pkg.Name, file.Name.Name)
+		}
+		pkg.Files = append(pkg.Files, &PackageFile{
+			Filename: fset.Position(file.Package).Filename,
+			File:     file,
+		})
+	}
+	sort.SliceStable(pkg.Files, func(i, j int) bool {
+		return pkg.Files[i].Filename < pkg.Files[j].Filename
+	})
+	return pkg, nil
+}
+
+// Search Runs the search function on every file of the package, in the order of Files. Returns all matches together
+// with the file they were found in and their position.
+func (p *Package) Search(searchFunction func(node ast.Node, path Path) bool, opts ...SearchOption) []*FileMatch {
+	matches, _ := p.SearchContext(context.Background(), searchFunction, opts...)
+	return matches
+}
+
+// SearchContext Works like Search, but terminates the search once ctx is done. Returns the matches found until then
+// and ctx.Err() if the search was cancelled.
+func (p *Package) SearchContext(ctx context.Context, searchFunction func(node ast.Node, path Path) bool, opts ...SearchOption) ([]*FileMatch, error) {
+	var matches []*FileMatch
+	for _, file := range p.Files {
+		fileMatches, err := p.searchFile(ctx, file, searchFunction, opts)
+		matches = append(matches, fileMatches...)
+		if err != nil {
+			return matches, err
+		}
+	}
+	return matches, nil
+}
+
+// searchFile Runs the search function on a single file of the package. The search ends early, once ctx is done.
+func (p *Package) searchFile(ctx context.Context, file *PackageFile, searchFunction func(node ast.Node, path Path) bool, opts []SearchOption) ([]*FileMatch, error) {
+	var matches []*FileMatch
+	_, err := walkPath(ctx, file.File, Path{}, func(node ast.Node, path Path) bool {
+		if searchFunction(node, path) {
+			found := node
+			matches = append(matches, &FileMatch{
+				FoundNodes: &FoundNodes{
+					Node:     &found,
+					Parents:  parentsOf(path, nil),
+					Path:     path.Clone(),
+					Location: Locate(node, p.Fset, file.Src),
+				},
+				File:     file.File,
+				Filename: file.Filename,
+				Position: p.Fset.Position(node.Pos()),
+			})
+		}
+		return true
+	}, opts)
+	return matches, err
+}
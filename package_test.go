@@ -0,0 +1,28 @@
+package AstUtils
+
+import (
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestLoadPackageSkipsExternalTests(t *testing.T) {
+	dir := filepath.Join(runtime.GOROOT(), "src", "strings")
+	pkg, err := LoadPackage(dir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg.Name != "strings" || pkg.Path != "strings" {
+		t.Errorf("loaded package %s with path %q, want strings", pkg.Name, pkg.Path)
+	}
+	tests := 0
+	for _, file := range pkg.Files {
+		if strings.HasSuffix(file.Filename, "_test.go") {
+			tests++
+		}
+	}
+	if tests == 0 {
+		t.Error("no test files of package strings loaded")
+	}
+}
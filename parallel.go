@@ -0,0 +1,60 @@
+package AstUtils
+
+import (
+	"context"
+	"go/ast"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// SearchParallel Runs the search function on all files of the package, using at most workers goroutines. A value
+// below one uses GOMAXPROCS workers. The search function is called concurrently for different files and must be safe
+// for concurrent use. The matches are sorted by filename and position, nodes starting at the same position keep their
+// traversal order. If the context got cancelled before all files were searched, the matches found until then are
+// returned, sorted the same way, together with ctx.Err().
+func (p *Package) SearchParallel(ctx context.Context, workers int, searchFunction func(node ast.Node, path Path) bool, opts ...SearchOption) ([]*FileMatch, error) {
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	results := make([][]*FileMatch, len(p.Files))
+	errs := make([]error, len(p.Files))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				results[job], errs[job] = p.searchFile(ctx, p.Files[job], searchFunction, opts)
+			}
+		}()
+	}
+sendJobs:
+	for i := range p.Files {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break sendJobs
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	var matches []*FileMatch
+	for _, result := range results {
+		matches = append(matches, result...)
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Filename != matches[j].Filename {
+			return matches[i].Filename < matches[j].Filename
+		}
+		return matches[i].Position.Offset < matches[j].Position.Offset
+	})
+	for _, err := range errs {
+		if err != nil {
+			return matches, err
+		}
+	}
+	return matches, ctx.Err()
+}
@@ -0,0 +1,87 @@
+package AstUtils
+
+import (
+	"context"
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+)
+
+// parallelPackage Returns a package of several files, added to the FileSet in reverse filename order.
+func parallelPackage(t *testing.T) *Package {
+	t.Helper()
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range []string{"c.go", "b.go", "a.go"} {
+		src := "package p\n\nfunc " + name[:1] + "(x, y int) int {\n\treturn x + y\n}\n\nvar v" + name[:1] + " = " + name[:1] + "(1, 2)\n"
+		file, err := parser.ParseFile(fset, name, src, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, file)
+	}
+	pkg, err := NewPackage(fset, files...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pkg
+}
+
+func isIdent(node ast.Node, path Path) bool {
+	_, ok := node.(*ast.Ident)
+	return ok
+}
+
+func TestSearchParallelOrder(t *testing.T) {
+	pkg := parallelPackage(t)
+	want, err := pkg.SearchContext(context.Background(), isIdent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, workers := range []int{0, 1, 3, 8} {
+		got, err := pkg.SearchParallel(context.Background(), workers, isIdent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		compareFileMatches(t, got, want)
+	}
+}
+
+func TestSearchParallelCancel(t *testing.T) {
+	pkg := parallelPackage(t)
+	all, err := pkg.SearchContext(context.Background(), isIdent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	found := 0
+	got, err := pkg.SearchParallel(ctx, 1, func(node ast.Node, path Path) bool {
+		if !isIdent(node, path) {
+			return false
+		}
+		if found++; found == 3 {
+			cancel()
+		}
+		return true
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got error %v, want %v", err, context.Canceled)
+	}
+	compareFileMatches(t, got, all[:3])
+}
+
+func compareFileMatches(t *testing.T, got, want []*FileMatch) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d matches, want %d", len(got), len(want))
+	}
+	for i := range got {
+		if *got[i].Node != *want[i].Node || got[i].Filename != want[i].Filename {
+			t.Fatalf("match %d is %s in %s, want %s in %s", i, NodeName(*got[i].Node), got[i].Filename,
+				NodeName(*want[i].Node), want[i].Filename)
+		}
+	}
+}
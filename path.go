@@ -0,0 +1,127 @@
+package AstUtils
+
+import (
+	"fmt"
+	"go/ast"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// PathStep describes one edge between a node and one of its children. Field is the name of the field of Parent that
+// holds the child, e.g. Decls, Specs or List. Index is the position of the child inside that field if it is a slice,
+// otherwise -1.
+type PathStep struct {
+	Parent ast.Node
+	Field  string
+	Index  int
+}
+
+// Path holds the ancestry of a node in root-to-leaf order. The first step starts at the root of the traversal, the
+// last step points to the slot of the node itself. The path of the root is empty.
+//
+// All traversals share a single path between the visited nodes, the path passed to a callback is only valid until the
+// callback returns, or for WalkShared until the next iteration. Use Clone to keep it. Paths yielded by Walk and paths
+// of returned matches are copies and stay valid.
+type Path []PathStep
+
+// Clone Returns a copy of the path, which stays valid after the traversal moved on.
+func (p Path) Clone() Path {
+	return slices.Clone(p)
+}
+
+// Parent Returns the direct parent of the node, or nil if the node is the root of the traversal.
+func (p Path) Parent() ast.Node {
+	if len(p) == 0 {
+		return nil
+	}
+	return p[len(p)-1].Parent
+}
+
+// Parents Returns all ancestors of the node in root-to-leaf order.
+func (p Path) Parents() []ast.Node {
+	parents := make([]ast.Node, len(p))
+	for i, step := range p {
+		parents[i] = step.Parent
+	}
+	return parents
+}
+
+// String Returns the location of the node as string, e.g. Decls[3].Specs[0].Type.Fields.List[2].Type. The string
+// can be resolved back to the node with ResolvePath, in the same tree or in a tree parsed from the same source.
+func (p Path) String() string {
+	var b strings.Builder
+	for i, step := range p {
+		if i > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(step.Field)
+		if step.Index >= 0 {
+			b.WriteString("[" + strconv.Itoa(step.Index) + "]")
+		}
+	}
+	return b.String()
+}
+
+// ResolvePath Returns the node the path string points to, starting at root, together with its Path. See Path.String
+// for the format. An empty string resolves to root.
+func ResolvePath(root ast.Node, path string) (ast.Node, Path, error) {
+	node := root
+	resolved := Path{}
+	if path == "" {
+		return node, resolved, nil
+	}
+	for _, segment := range strings.Split(path, ".") {
+		step, err := parsePathStep(segment)
+		if err != nil {
+			return nil, nil, fmt.Errorf("path %s: %w", path, err)
+		}
+		step.Parent = node
+		field, err := fieldOf(step)
+		if err != nil {
+			return nil, nil, fmt.Errorf("path %s: %w", path, err)
+		}
+		if step.Index >= 0 {
+			if field.Kind() != reflect.Slice {
+				return nil, nil, fmt.Errorf("path %s: %s is not a list", path, stepName(step))
+			}
+			if step.Index >= field.Len() {
+				return nil, nil, fmt.Errorf("path %s: index %d of %s out of range", path, step.Index, stepName(step))
+			}
+			field = field.Index(step.Index)
+		} else if field.Kind() == reflect.Slice {
+			return nil, nil, fmt.Errorf("path %s: %s is a list and needs an index", path, stepName(step))
+		}
+		child, ok := field.Interface().(ast.Node)
+		if !ok || isNilNode(child) {
+			return nil, nil, fmt.Errorf("path %s: %s holds no node", path, stepName(step))
+		}
+		resolved = append(resolved, step)
+		node = child
+	}
+	return node, resolved, nil
+}
+
+// parsePathStep Parses a single segment of a path string, like List[2] or Type.
+func parsePathStep(segment string) (PathStep, error) {
+	step := PathStep{
+		Field: segment,
+		Index: -1,
+	}
+	if open := strings.IndexByte(segment, '['); open >= 0 {
+		if !strings.HasSuffix(segment, "]") {
+			return step, fmt.Errorf("invalid segment %q", segment)
+		}
+		index, err := strconv.Atoi(segment[open+1 : len(segment)-1])
+		if err != nil || index < 0 {
+			return step, fmt.Errorf("invalid index in segment %q", segment)
+		}
+		step.Field = segment[:open]
+		step.Index = index
+	}
+	if step.Field == "" {
+		return step, fmt.Errorf("invalid segment %q", segment)
+	}
+	return step, nil
+}
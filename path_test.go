@@ -0,0 +1,128 @@
+package AstUtils
+
+import (
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const pathSrc = `package p
+
+import "fmt"
+
+type User struct {
+	Name, Email string
+	Age         int
+}
+
+func (u *User) String() string {
+	if u == nil {
+		return ""
+	}
+	return fmt.Sprint(u.Name, u.Age)
+}
+
+func main() {}
+`
+
+func TestResolvePath(t *testing.T) {
+	file, err := parser.ParseFile(token.NewFileSet(), "path.go", pathSrc, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path string
+		want string
+		// wantErr is a part of the expected error message
+		wantErr string
+	}{
+		{path: "", want: "*ast.File"},
+		{path: "Name", want: "*ast.Ident"},
+		{path: "Decls[1].Specs[0].Type.Fields.List[0].Names[1]", want: "*ast.Ident"},
+		{path: "Decls[2].Recv.List[0].Type", want: "*ast.StarExpr"},
+		{path: "Decls[2].Body.List[0].Body.List[0]", want: "*ast.ReturnStmt"},
+		{path: "Decls", wantErr: "File.Decls is a list and needs an index"},
+		{path: "Decls[1].Specs[0].Type.Fields.List", wantErr: "FieldList.List is a list and needs an index"},
+		{path: "Decls[4]", wantErr: "index 4 of File.Decls out of range"},
+		{path: "Decls[2].Body.List[2]", wantErr: "index 2 of BlockStmt.List out of range"},
+		{path: "Name[0]", wantErr: "File.Name is not a list"},
+		{path: "Decls[2].Body[0]", wantErr: "FuncDecl.Body is not a list"},
+		{path: "Decls[3].Recv", wantErr: "FuncDecl.Recv holds no node"},
+		{path: "Decls[2].Body.List[0].Else", wantErr: "IfStmt.Else holds no node"},
+		{path: "Decls[2].Name.Name", wantErr: "Ident.Name holds no node"},
+		{path: "Decls[0].Spec", wantErr: "*ast.GenDecl has no field Spec"},
+		{path: "Decls[x]", wantErr: "invalid index"},
+		{path: "Decls[-1]", wantErr: "invalid index"},
+		{path: "Decls[0", wantErr: "invalid segment"},
+		{path: "Decls[0]..Name", wantErr: "invalid segment"},
+		{path: "[0]", wantErr: "invalid segment"},
+	}
+	for _, test := range tests {
+		node, path, err := ResolvePath(file, test.path)
+		if test.wantErr != "" {
+			if err == nil {
+				t.Errorf("%q: resolved to %T, want an error", test.path, node)
+			} else if msg := err.Error(); !strings.HasPrefix(msg, "path "+test.path+": ") || !strings.Contains(msg, test.wantErr) {
+				t.Errorf("%q: error %q, want %q", test.path, err, test.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", test.path, err)
+			continue
+		}
+		if got := reflect.TypeOf(node).String(); got != test.want {
+			t.Errorf("%q: resolved to %s, want %s", test.path, got, test.want)
+		}
+		if path.String() != test.path {
+			t.Errorf("%q: resolved path prints as %q", test.path, path.String())
+		}
+	}
+}
+
+func TestResolvePathRoundTrip(t *testing.T) {
+	file, err := parser.ParseFile(token.NewFileSet(), "path.go", pathSrc, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reparsed, err := parser.ParseFile(token.NewFileSet(), "path.go", pathSrc, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes := 0
+	for node, path := range Walk(file) {
+		nodes++
+		resolved, resolvedPath, err := ResolvePath(reparsed, path.String())
+		if err != nil {
+			t.Errorf("%s: %v", path, err)
+			continue
+		}
+		if reflect.TypeOf(resolved) != reflect.TypeOf(node) {
+			t.Errorf("%s: resolved to %T, want %T", path, resolved, node)
+			continue
+		}
+		if resolved.Pos()-reparsed.FileStart != node.Pos()-file.FileStart ||
+			resolved.End()-reparsed.FileStart != node.End()-file.FileStart {
+			t.Errorf("%s: resolved %T at a different offset", path, node)
+		}
+		if len(resolvedPath) != len(path) {
+			t.Errorf("%s: resolved path has %d steps, want %d", path, len(resolvedPath), len(path))
+			continue
+		}
+		for i, step := range resolvedPath {
+			if step.Field != path[i].Field || step.Index != path[i].Index ||
+				reflect.TypeOf(step.Parent) != reflect.TypeOf(path[i].Parent) {
+				t.Errorf("%s: step %d resolved to %s of %T", path, i, stepName(step), step.Parent)
+			}
+		}
+		// the path of the original tree leads back to the node itself
+		if same, _, err := ResolvePath(file, path.String()); err != nil || same != node {
+			t.Errorf("%s: doesn't resolve to the node in its own tree: %v", path, err)
+		}
+	}
+	if nodes < 50 {
+		t.Errorf("walked only %d nodes", nodes)
+	}
+}
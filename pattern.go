@@ -0,0 +1,429 @@
+package AstUtils
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"reflect"
+	"strings"
+)
+
+const (
+	metaPrefix     = "__astutils_meta_"
+	metaListPrefix = "__astutils_list_"
+)
+
+// Pattern is a compiled structural pattern, see CompilePattern.
+type Pattern struct {
+	src   string
+	nodes []ast.Node
+	stmts bool
+}
+
+// Binding holds the nodes a metavariable got bound to. $name binds exactly one node, $*name binds any number of
+// consecutive list elements.
+type Binding struct {
+	Nodes []ast.Node
+	List  bool
+}
+
+// Node Returns the bound node of a $name metavariable, or the first node of a $*name metavariable.
+func (b Binding) Node() ast.Node {
+	if len(b.Nodes) == 0 {
+		return nil
+	}
+	return b.Nodes[0]
+}
+
+// Bindings maps the names of metavariables, without the leading $ or $*, to their bound nodes.
+type Bindings map[string]Binding
+
+// PatternMatch holds a match of a Pattern. For patterns consisting of multiple statements, Nodes holds all matched
+// statements, Node the first of them and Path the path of the first statement. Otherwise, Nodes only holds Node.
+type PatternMatch struct {
+	Node     ast.Node
+	Nodes    []ast.Node
+	Path     Path
+	Bindings Bindings
+}
+
+// CompilePattern Compiles a snippet of Go code into a Pattern. The snippet may be an expression, one or more
+// statements or a declaration. Metavariables can be used in place of any node:
+//
+//	$name   matches any single node. All occurrences of the same name must match equal nodes.
+//	$*name  matches any number of consecutive elements of a list, e.g. arguments, statements or fields.
+//	$_, $*_ work like the above, without binding the matched nodes.
+//
+// For example `fmt.Errorf($msg, $*args)` or `if $err != nil { return $*_ }`. Positions, comments and the
+// resolution of identifiers are ignored while matching, see NodesEqual.
+func CompilePattern(src string) (*Pattern, error) {
+	nodes, stmts, err := parsePattern(src)
+	if err != nil {
+		return nil, err
+	}
+	return &Pattern{
+		src:   src,
+		nodes: nodes,
+		stmts: stmts,
+	}, nil
+}
+
+// MustCompilePattern Works like CompilePattern, but panics if the pattern can't be compiled.
+func MustCompilePattern(src string) *Pattern {
+	pattern, err := CompilePattern(src)
+	if err != nil {
+		panic(err)
+	}
+	return pattern
+}
+
+// String Returns the source the pattern was compiled from.
+func (p *Pattern) String() string {
+	return p.src
+}
+
+// Match Reports whether node matches the pattern and returns the bound metavariables. Patterns consisting of
+// multiple statements never match a single node, use FindAll instead.
+func (p *Pattern) Match(node ast.Node) (Bindings, bool) {
+	if p.stmts {
+		return nil, false
+	}
+	m := &patternMatcher{bindings: Bindings{}}
+	if !m.match(p.nodes[0], node) {
+		return nil, false
+	}
+	return m.bindings, true
+}
+
+// FindAll Returns all matches of the pattern below and including root, in traversal order. Patterns consisting of
+// multiple statements match consecutive statements of blocks and case clauses.
+func (p *Pattern) FindAll(root ast.Node, opts ...SearchOption) []*PatternMatch {
+	var matches []*PatternMatch
+	for node, path := range WalkShared(root, opts...) {
+		if !p.stmts {
+			if bindings, ok := p.Match(node); ok {
+				matches = append(matches, &PatternMatch{
+					Node:     node,
+					Nodes:    []ast.Node{node},
+					Path:     path.Clone(),
+					Bindings: bindings,
+				})
+			}
+			continue
+		}
+		list, field := stmtList(node)
+		for start := 0; start < len(list); start++ {
+			for end := len(list); end > start; end-- {
+				m := &patternMatcher{bindings: Bindings{}}
+				if !m.matchList(p.nodes, list[start:end]) {
+					continue
+				}
+				matches = append(matches, &PatternMatch{
+					Node:  list[start],
+					Nodes: list[start:end],
+					Path: append(path[:len(path):len(path)], PathStep{
+						Parent: node,
+						Field:  field,
+						Index:  start,
+					}),
+					Bindings: m.bindings,
+				})
+				start = end - 1
+				break
+			}
+		}
+	}
+	return matches
+}
+
+// stmtList Returns the statements of a node holding a statement list, together with the name of the field.
+func stmtList(node ast.Node) ([]ast.Node, string) {
+	var stmts []ast.Stmt
+	var field string
+	switch n := node.(type) {
+	case *ast.BlockStmt:
+		stmts, field = n.List, "List"
+	case *ast.CaseClause:
+		stmts, field = n.Body, "Body"
+	case *ast.CommClause:
+		stmts, field = n.Body, "Body"
+	default:
+		return nil, ""
+	}
+	list := make([]ast.Node, len(stmts))
+	for i, stmt := range stmts {
+		list[i] = stmt
+	}
+	return list, field
+}
+
+// parsePattern Replaces the metavariables of src by identifiers and parses it. Returns the parsed nodes and whether
+// they are a list of statements.
+func parsePattern(src string) ([]ast.Node, bool, error) {
+	src, err := replaceMetavariables(src)
+	if err != nil {
+		return nil, false, err
+	}
+	if expr, err := parser.ParseExpr(src); err == nil {
+		return []ast.Node{expr}, false, nil
+	}
+	if file, err := parser.ParseFile(token.NewFileSet(), "", "package p\nfunc _() {\n"+src+"\n}", parser.SkipObjectResolution); err == nil {
+		stmts := file.Decls[0].(*ast.FuncDecl).Body.List
+		if len(stmts) == 0 {
+			return nil, false, errors.New("empty pattern")
+		}
+		if declStmt, ok := stmts[0].(*ast.DeclStmt); ok && len(stmts) == 1 {
+			return []ast.Node{declStmt.Decl}, false, nil
+		}
+		nodes := make([]ast.Node, len(stmts))
+		for i, stmt := range stmts {
+			nodes[i] = stmt
+		}
+		return nodes, len(nodes) > 1, nil
+	}
+	file, err := parser.ParseFile(token.NewFileSet(), "", "package p\n"+src, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, false, fmt.Errorf("pattern is no expression, statement or declaration: %w", err)
+	}
+	if len(file.Decls) != 1 {
+		return nil, false, errors.New("pattern must contain exactly one declaration")
+	}
+	return []ast.Node{file.Decls[0]}, false, nil
+}
+
+// replaceMetavariables Replaces $name and $*name outside of literals and comments by identifiers, which are
+// recognized by metaName.
+func replaceMetavariables(src string) (string, error) {
+	var s scanner.Scanner
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+	s.Init(file, []byte(src), nil, scanner.ScanComments)
+
+	var b strings.Builder
+	last := 0
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok != token.ILLEGAL || lit != "$" {
+			continue
+		}
+		offset := file.Offset(pos)
+		prefix := metaPrefix
+		pos, tok, lit = s.Scan()
+		if tok == token.MUL && file.Offset(pos) == offset+1 {
+			prefix = metaListPrefix
+			pos, tok, lit = s.Scan()
+		}
+		if tok != token.IDENT {
+			return "", fmt.Errorf("metavariable at offset %d has no name", offset)
+		}
+		b.WriteString(src[last:offset])
+		b.WriteString(prefix + lit)
+		last = file.Offset(pos) + len(lit)
+	}
+	b.WriteString(src[last:])
+	return b.String(), nil
+}
+
+// metaName Returns the name of the metavariable node stands for and whether it's a list metavariable. Besides
+// identifiers, expression statements and unnamed fields consisting only of a metavariable stand for it, so $x can be
+// used as a statement and $*x inside field lists.
+func metaName(node ast.Node) (string, bool, bool) {
+	switch n := node.(type) {
+	case *ast.Ident:
+		if n == nil {
+			return "", false, false
+		}
+		if name, ok := strings.CutPrefix(n.Name, metaPrefix); ok {
+			return name, false, true
+		}
+		if name, ok := strings.CutPrefix(n.Name, metaListPrefix); ok {
+			return name, true, true
+		}
+	case *ast.ExprStmt:
+		if n != nil {
+			return metaName(n.X)
+		}
+	case *ast.Field:
+		if n != nil && len(n.Names) == 0 && n.Tag == nil {
+			if ident, ok := n.Type.(*ast.Ident); ok {
+				return metaName(ident)
+			}
+		}
+	}
+	return "", false, false
+}
+
+// patternMatcher matches pattern nodes against nodes, collecting the bindings of the metavariables.
+type patternMatcher struct {
+	bindings Bindings
+}
+
+var (
+	posType          = reflect.TypeOf(token.NoPos)
+	nodeType         = reflect.TypeOf((*ast.Node)(nil)).Elem()
+	commentGroupType = reflect.TypeOf((*ast.CommentGroup)(nil))
+	objectType       = reflect.TypeOf((*ast.Object)(nil))
+	scopeType        = reflect.TypeOf((*ast.Scope)(nil))
+)
+
+// significantPos holds the names of the position fields, whose presence changes the meaning of a node: the ... of a
+// CallExpr spreading its last argument and the = of a TypeSpec declaring an alias.
+var significantPos = map[string]bool{
+	"Ellipsis": true,
+	"Assign":   true,
+}
+
+// match Reports whether node matches pattern. Bindings are only valid if true is returned.
+func (m *patternMatcher) match(pattern, node ast.Node) bool {
+	if name, _, ok := metaName(pattern); ok {
+		return m.bind(name, Binding{Nodes: []ast.Node{node}})
+	}
+	if isNilNode(pattern) || isNilNode(node) {
+		return isNilNode(pattern) && isNilNode(node)
+	}
+	return m.matchValue(reflect.ValueOf(pattern), reflect.ValueOf(node))
+}
+
+// bind Binds name to b, or checks that the previously bound nodes equal the nodes of b.
+func (m *patternMatcher) bind(name string, b Binding) bool {
+	if name == "_" {
+		return true
+	}
+	bound, ok := m.bindings[name]
+	if !ok {
+		m.bindings[name] = b
+		return true
+	}
+	if len(bound.Nodes) != len(b.Nodes) {
+		return false
+	}
+	for i := range bound.Nodes {
+		if !NodesEqual(bound.Nodes[i], b.Nodes[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchValue Compares two values of the ast recursively.
+func (m *patternMatcher) matchValue(pattern, node reflect.Value) bool {
+	if pattern.Type() != node.Type() {
+		return false
+	}
+	switch pattern.Kind() {
+	case reflect.Interface:
+		if pattern.IsNil() || node.IsNil() {
+			return pattern.IsNil() && node.IsNil()
+		}
+		return m.match(pattern.Interface().(ast.Node), node.Interface().(ast.Node))
+	case reflect.Pointer:
+		if pattern.IsNil() || node.IsNil() {
+			return pattern.IsNil() && node.IsNil()
+		}
+		if p, ok := pattern.Interface().(ast.Node); ok {
+			if _, _, isMeta := metaName(p); isMeta {
+				return m.match(p, node.Interface().(ast.Node))
+			}
+		}
+		return m.matchValue(pattern.Elem(), node.Elem())
+	case reflect.Struct:
+		for i := 0; i < pattern.NumField(); i++ {
+			field := pattern.Type().Field(i)
+			switch field.Type {
+			case posType:
+				if significantPos[field.Name] &&
+					pattern.Field(i).Interface().(token.Pos).IsValid() != node.Field(i).Interface().(token.Pos).IsValid() {
+					return false
+				}
+				continue
+			case commentGroupType, objectType, scopeType:
+				continue
+			}
+			if field.Name == "Incomplete" {
+				continue
+			}
+			if !m.matchValue(pattern.Field(i), node.Field(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Slice:
+		if pattern.Type().Elem().Implements(nodeType) {
+			return m.matchList(nodeList(pattern), nodeList(node))
+		}
+		if pattern.Len() != node.Len() {
+			return false
+		}
+		for i := 0; i < pattern.Len(); i++ {
+			if !m.matchValue(pattern.Index(i), node.Index(i)) {
+				return false
+			}
+		}
+		return true
+	}
+	return pattern.Interface() == node.Interface()
+}
+
+// matchList Matches a list of pattern nodes against a list of nodes. $*name metavariables match any number of
+// consecutive nodes, the shortest possible match is tried first.
+func (m *patternMatcher) matchList(patterns, nodes []ast.Node) bool {
+	if len(patterns) == 0 {
+		return len(nodes) == 0
+	}
+	if name, isList, ok := metaName(patterns[0]); ok && isList {
+		for i := 0; i <= len(nodes); i++ {
+			saved := m.save()
+			if m.bind(name, Binding{Nodes: nodes[:i:i], List: true}) && m.matchList(patterns[1:], nodes[i:]) {
+				return true
+			}
+			m.bindings = saved
+		}
+		return false
+	}
+	if len(nodes) == 0 {
+		return false
+	}
+	saved := m.save()
+	if m.match(patterns[0], nodes[0]) && m.matchList(patterns[1:], nodes[1:]) {
+		return true
+	}
+	m.bindings = saved
+	return false
+}
+
+// save Returns a copy of the current bindings, which can be restored once a match attempt failed.
+func (m *patternMatcher) save() Bindings {
+	saved := make(Bindings, len(m.bindings))
+	for name, b := range m.bindings {
+		saved[name] = b
+	}
+	return saved
+}
+
+// NodesEqual Reports whether a and b are structurally equal, ignoring positions, comments and the resolution of
+// identifiers. Only the presence of positions changing the meaning of a node is compared, like the ... of f(xs...) and
+// the = of type A = B.
+func NodesEqual(a, b ast.Node) bool {
+	if isNilNode(a) || isNilNode(b) {
+		return isNilNode(a) && isNilNode(b)
+	}
+	m := &patternMatcher{bindings: Bindings{}}
+	return m.matchValue(reflect.ValueOf(a), reflect.ValueOf(b))
+}
+
+// nodeList Converts a slice of nodes of any node type into []ast.Node.
+func nodeList(v reflect.Value) []ast.Node {
+	list := make([]ast.Node, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		if n, ok := v.Index(i).Interface().(ast.Node); ok {
+			list = append(list, n)
+		}
+	}
+	return list
+}
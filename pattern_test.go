@@ -0,0 +1,135 @@
+package AstUtils
+
+import (
+	"bytes"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"maps"
+	"strings"
+	"testing"
+)
+
+// printBindings Returns the bound nodes of every metavariable, formatted and joined by a comma.
+func printBindings(t *testing.T, bindings Bindings) map[string]string {
+	t.Helper()
+	printed := map[string]string{}
+	for name, binding := range bindings {
+		var nodes []string
+		for _, node := range binding.Nodes {
+			var buf bytes.Buffer
+			if err := format.Node(&buf, token.NewFileSet(), node); err != nil {
+				t.Fatal(err)
+			}
+			nodes = append(nodes, buf.String())
+		}
+		printed[name] = strings.Join(nodes, ", ")
+	}
+	return printed
+}
+
+func TestPatternFindAll(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		body    string
+		want    []map[string]string
+	}{
+		{
+			name:    "call with list metavariable",
+			pattern: "fmt.Errorf($msg, $*args)",
+			body:    "_ = fmt.Errorf(\"a\")\n_ = fmt.Errorf(\"b %d %d\", x, y)\nfmt.Println(\"c\")",
+			want: []map[string]string{
+				{"msg": `"a"`, "args": ""},
+				{"msg": `"b %d %d"`, "args": "x, y"},
+			},
+		},
+		{
+			name:    "statement with ignored results",
+			pattern: "if $err != nil { return $*_ }",
+			body:    "if err != nil { return nil, err }\nif err != nil { log(err) }\nif x == nil { return }",
+			want:    []map[string]string{{"err": "err"}},
+		},
+		{
+			name:    "repeated metavariable",
+			pattern: "$x == $x",
+			body:    "_ = a == a\n_ = a == b\n_ = f(1) == f(1)\n_ = f(1) == f(2)",
+			want:    []map[string]string{{"x": "a"}, {"x": "f(1)"}},
+		},
+		{
+			name:    "repeated list metavariable",
+			pattern: "f($*a, $*a)",
+			body:    "f(1, 2, 1, 2)\nf(1, 2, 1)\nf()",
+			want:    []map[string]string{{"a": "1, 2"}, {"a": ""}},
+		},
+		{
+			name:    "call without spread",
+			pattern: "f($x)",
+			body:    "f(xs...)\nf(x)",
+			want:    []map[string]string{{"x": "x"}},
+		},
+		{
+			name:    "call with spread",
+			pattern: "f($x...)",
+			body:    "f(xs...)\nf(x)",
+			want:    []map[string]string{{"x": "xs"}},
+		},
+		{
+			name:    "alias",
+			pattern: "type $T = $U",
+			body:    "type X int\ntype Y = int",
+			want:    []map[string]string{{"T": "Y", "U": "int"}},
+		},
+		{
+			name:    "type definition",
+			pattern: "type $T $U",
+			body:    "type X int\ntype Y = int",
+			want:    []map[string]string{{"T": "X", "U": "int"}},
+		},
+		{
+			name:    "statements",
+			pattern: "$x := $v; return $x",
+			body:    "a := 1\nreturn a\nb := 2\nreturn a",
+			want:    []map[string]string{{"x": "a", "v": "1"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			file, err := parser.ParseFile(token.NewFileSet(), "test.go", "package p\n\nfunc f() {\n"+test.body+"\n}\n", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pattern, err := CompilePattern(test.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+			matches := pattern.FindAll(file)
+			if len(matches) != len(test.want) {
+				t.Fatalf("got %d matches, want %d", len(matches), len(test.want))
+			}
+			for i, match := range matches {
+				if got := printBindings(t, match.Bindings); !maps.Equal(got, test.want[i]) {
+					t.Errorf("match %d bound %v, want %v", i, got, test.want[i])
+				}
+				if match.Node == nil || len(match.Path) == 0 {
+					t.Errorf("match %d has no node or path", i)
+				}
+			}
+		})
+	}
+}
+
+func TestCompilePatternErrors(t *testing.T) {
+	for _, src := range []string{
+		"",
+		"f(",
+		"$",
+		"f($*)",
+		"if {",
+		"func f() {}; func g() {}",
+	} {
+		if _, err := CompilePattern(src); err == nil {
+			t.Errorf("pattern %q compiled, want an error", src)
+		}
+	}
+}
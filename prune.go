@@ -0,0 +1,47 @@
+package AstUtils
+
+import (
+	"context"
+	"go/ast"
+)
+
+// Action tells the traversal how to proceed after a node has been inspected.
+type Action int
+
+const (
+	// Continue descends into the children of the node.
+	Continue Action = iota
+	// SkipChildren continues with the next sibling, without descending into the children of the node.
+	SkipChildren
+	// Stop terminates the whole traversal.
+	Stop
+)
+
+// SearchTree Searches the Ast-tree like SearchNodes, but lets the search function control the traversal. Besides
+// reporting a match, the search function returns an Action, which allows to skip the children of a node or to stop
+// the search. For example returning SkipChildren for *ast.FuncDecl restricts a search to the top level declarations
+// of a file, without descending into function bodies.
+func SearchTree(root ast.Node, searchFunction func(node ast.Node, path Path) (bool, Action), opts ...SearchOption) []*FoundNodes {
+	foundNodes, _ := SearchTreeContext(context.Background(), root, searchFunction, opts...)
+	return foundNodes
+}
+
+// SearchTreeContext Works like SearchTree, but terminates the search once ctx is done. Returns the matches found until
+// then and ctx.Err() if the search was cancelled.
+func SearchTreeContext(ctx context.Context, root ast.Node, searchFunction func(node ast.Node, path Path) (bool, Action), opts ...SearchOption) ([]*FoundNodes, error) {
+	var foundNodes []*FoundNodes
+	_, err := walkPruned(ctx, root, Path{}, func(node ast.Node, path Path) Action {
+		match, action := searchFunction(node, path)
+		if match {
+			// only matches need their own variable, so the other nodes don't escape
+			found := node
+			foundNodes = append(foundNodes, &FoundNodes{
+				Node:    &found,
+				Parents: parentsOf(path, nil),
+				Path:    path.Clone(),
+			})
+		}
+		return action
+	}, nil, opts)
+	return foundNodes, err
+}
@@ -0,0 +1,373 @@
+package AstUtils
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Query is a compiled selector query, see CompileQuery.
+type Query struct {
+	src       string
+	selectors []*selector
+}
+
+type selector struct {
+	// child is set, if the selector has to match the direct parent of the node matched by the next selector, instead
+	// of any ancestor
+	child      bool
+	kind       string
+	predicates []*predicate
+}
+
+type predicate struct {
+	attribute string
+	operator  string
+	value     string
+	re        *regexp.Regexp
+}
+
+// CompileQuery Compiles a selector query. A query consists of selectors separated by combinators, like CSS:
+//
+//	A B    matches nodes matching B, that have an ancestor matching A
+//	A > B  matches nodes matching B, whose direct parent matches A
+//
+// A selector starts with the kind of the node, which is the name of its go/ast type without package, e.g. TypeSpec, or
+// * for any node. Unknown kinds are an error. It's followed by any number of attribute predicates in brackets. [Attr]
+// requires the attribute to be non-empty, [Attr op value] compares it using one of the operators = (equal), != (not
+// equal), *= (contains), ^= (starts with), $= (ends with) and ~= (matches the regular expression). Values may be
+// quoted. The attributes are:
+//
+//	Name     the name of the node, see NodeName
+//	Kind     the token of basic literals (e.g. STRING), declarations (e.g. type), assignments and operators,
+//	         otherwise the kind of the node
+//	Tag      the tag of a field without backticks
+//	Tag.key  the value of key inside the tag of a field
+//	Type     the type expression of fields, value specs and type specs, e.g. []string
+//
+// For example `TypeSpec[Name=User] > StructType Field[Tag*=json]`. Note that the fields of a struct are children of
+// its FieldList, so `StructType > FieldList > Field` selects the direct fields of a struct.
+func CompileQuery(src string) (*Query, error) {
+	q := &Query{src: src}
+	p := &queryParser{src: []rune(src)}
+	child := false
+	for {
+		p.skipSpaces()
+		if p.done() {
+			break
+		}
+		if p.peek() == '>' {
+			if child || len(q.selectors) == 0 {
+				return nil, p.errorf("unexpected >")
+			}
+			p.pos++
+			child = true
+			continue
+		}
+		s, err := p.parseSelector()
+		if err != nil {
+			return nil, err
+		}
+		s.child = child
+		child = false
+		q.selectors = append(q.selectors, s)
+	}
+	if len(q.selectors) == 0 {
+		return nil, fmt.Errorf("empty query")
+	}
+	if child {
+		return nil, p.errorf("query ends with >")
+	}
+	return q, nil
+}
+
+// MustCompileQuery Works like CompileQuery, but panics if the query can't be compiled.
+func MustCompileQuery(src string) *Query {
+	q, err := CompileQuery(src)
+	if err != nil {
+		panic(err)
+	}
+	return q
+}
+
+// String Returns the source the query was compiled from.
+func (q *Query) String() string {
+	return q.src
+}
+
+// Matches Reports whether the node with the given path matches the query. It can be used as search function for
+// Walk filters and Package.Search.
+func (q *Query) Matches(node ast.Node, path Path) bool {
+	return q.matchAt(len(q.selectors)-1, node, path, len(path))
+}
+
+// Search Returns all nodes below and including root matching the query, in traversal order.
+func (q *Query) Search(root ast.Node, opts ...SearchOption) []*FoundNodes {
+	return SearchTree(root, func(node ast.Node, path Path) (bool, Action) {
+		return q.Matches(node, path), Continue
+	}, opts...)
+}
+
+// matchAt Reports whether the node at depth i matches selector s and the selectors before s match its ancestors. The
+// node at depth len(path) is node itself, the others are the parents in path.
+func (q *Query) matchAt(s int, node ast.Node, path Path, i int) bool {
+	current := node
+	if i < len(path) {
+		current = path[i].Parent
+	}
+	if !q.selectors[s].matches(current) {
+		return false
+	}
+	if s == 0 {
+		return true
+	}
+	if q.selectors[s].child {
+		return i > 0 && q.matchAt(s-1, node, path, i-1)
+	}
+	for j := i - 1; j >= 0; j-- {
+		if q.matchAt(s-1, node, path, j) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *selector) matches(node ast.Node) bool {
+	if s.kind != "*" && NodeKind(node) != s.kind {
+		return false
+	}
+	for _, p := range s.predicates {
+		if !p.matches(node) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *predicate) matches(node ast.Node) bool {
+	value, ok := queryAttribute(node, p.attribute)
+	switch p.operator {
+	case "":
+		return ok && value != ""
+	case "=":
+		return ok && value == p.value
+	case "!=":
+		return !ok || value != p.value
+	case "*=":
+		return ok && strings.Contains(value, p.value)
+	case "^=":
+		return ok && strings.HasPrefix(value, p.value)
+	case "$=":
+		return ok && strings.HasSuffix(value, p.value)
+	case "~=":
+		return ok && p.re.MatchString(value)
+	}
+	return false
+}
+
+// queryAttribute Returns the value of an attribute of node and whether the node has the attribute.
+func queryAttribute(node ast.Node, attribute string) (string, bool) {
+	switch attribute {
+	case "Name":
+		return NodeName(node), true
+	case "Kind":
+		switch n := node.(type) {
+		case *ast.BasicLit:
+			return n.Kind.String(), true
+		case *ast.GenDecl:
+			return n.Tok.String(), true
+		case *ast.AssignStmt:
+			return n.Tok.String(), true
+		case *ast.IncDecStmt:
+			return n.Tok.String(), true
+		case *ast.BranchStmt:
+			return n.Tok.String(), true
+		case *ast.BinaryExpr:
+			return n.Op.String(), true
+		case *ast.UnaryExpr:
+			return n.Op.String(), true
+		}
+		return NodeKind(node), true
+	case "Type":
+		switch n := node.(type) {
+		case *ast.Field:
+			return types.ExprString(n.Type), true
+		case *ast.ValueSpec:
+			if n.Type != nil {
+				return types.ExprString(n.Type), true
+			}
+		case *ast.TypeSpec:
+			return types.ExprString(n.Type), true
+		}
+		return "", false
+	}
+	field, ok := node.(*ast.Field)
+	if !ok || field.Tag == nil {
+		return "", false
+	}
+	if attribute == "Tag" {
+		return strings.Trim(field.Tag.Value, "`"), true
+	}
+	if key, ok := strings.CutPrefix(attribute, "Tag."); ok {
+		if values, ok := ExtractTagsByKey(field.Tag)[key]; ok {
+			return values[0], true
+		}
+	}
+	return "", false
+}
+
+// nodeKinds holds the kinds of all nodes of go/ast, which are the names of their types.
+var nodeKinds = map[string]bool{
+	"Comment": true, "CommentGroup": true, "Field": true, "FieldList": true,
+	"BadExpr": true, "Ident": true, "Ellipsis": true, "BasicLit": true, "FuncLit": true, "CompositeLit": true,
+	"ParenExpr": true, "SelectorExpr": true, "IndexExpr": true, "IndexListExpr": true, "SliceExpr": true,
+	"TypeAssertExpr": true, "CallExpr": true, "StarExpr": true, "UnaryExpr": true, "BinaryExpr": true,
+	"KeyValueExpr": true, "ArrayType": true, "StructType": true, "FuncType": true, "InterfaceType": true,
+	"MapType": true, "ChanType": true,
+	"BadStmt": true, "DeclStmt": true, "EmptyStmt": true, "LabeledStmt": true, "ExprStmt": true, "SendStmt": true,
+	"IncDecStmt": true, "AssignStmt": true, "GoStmt": true, "DeferStmt": true, "ReturnStmt": true,
+	"BranchStmt": true, "BlockStmt": true, "IfStmt": true, "CaseClause": true, "SwitchStmt": true,
+	"TypeSwitchStmt": true, "CommClause": true, "SelectStmt": true, "ForStmt": true, "RangeStmt": true,
+	"ImportSpec": true, "ValueSpec": true, "TypeSpec": true,
+	"BadDecl": true, "GenDecl": true, "FuncDecl": true,
+	"File": true, "Package": true,
+}
+
+// queryParser splits a query into selectors.
+type queryParser struct {
+	src []rune
+	pos int
+}
+
+func (p *queryParser) done() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *queryParser) peek() rune {
+	return p.src[p.pos]
+}
+
+func (p *queryParser) skipSpaces() {
+	for !p.done() && unicode.IsSpace(p.peek()) {
+		p.pos++
+	}
+}
+
+func (p *queryParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("query %q at %d: %s", string(p.src), p.pos, fmt.Sprintf(format, args...))
+}
+
+// word Reads an identifier, possibly containing dots.
+func (p *queryParser) word() string {
+	start := p.pos
+	for !p.done() && (unicode.IsLetter(p.peek()) || unicode.IsDigit(p.peek()) || p.peek() == '_' || p.peek() == '.') {
+		p.pos++
+	}
+	return string(p.src[start:p.pos])
+}
+
+func (p *queryParser) parseSelector() (*selector, error) {
+	s := &selector{}
+	if p.peek() == '*' {
+		p.pos++
+		s.kind = "*"
+	} else {
+		start := p.pos
+		if s.kind = p.word(); s.kind == "" {
+			return nil, p.errorf("expected node kind, found %q", p.peek())
+		}
+		if !nodeKinds[s.kind] {
+			p.pos = start
+			return nil, p.errorf("unknown node kind %q", s.kind)
+		}
+	}
+	for !p.done() && p.peek() == '[' {
+		p.pos++
+		pred, err := p.parsePredicate()
+		if err != nil {
+			return nil, err
+		}
+		s.predicates = append(s.predicates, pred)
+	}
+	if !p.done() && !unicode.IsSpace(p.peek()) && p.peek() != '>' {
+		return nil, p.errorf("unexpected %q", p.peek())
+	}
+	return s, nil
+}
+
+func (p *queryParser) parsePredicate() (*predicate, error) {
+	p.skipSpaces()
+	pred := &predicate{attribute: p.word()}
+	switch pred.attribute {
+	case "Name", "Kind", "Tag", "Type":
+	default:
+		if !strings.HasPrefix(pred.attribute, "Tag.") {
+			return nil, p.errorf("unknown attribute %q", pred.attribute)
+		}
+	}
+	p.skipSpaces()
+	if p.done() {
+		return nil, p.errorf("unterminated predicate")
+	}
+	if p.peek() == ']' {
+		p.pos++
+		return pred, nil
+	}
+	for _, op := range []string{"!=", "*=", "^=", "$=", "~=", "="} {
+		if strings.HasPrefix(string(p.src[p.pos:]), op) {
+			pred.operator = op
+			p.pos += len(op)
+			break
+		}
+	}
+	if pred.operator == "" {
+		return nil, p.errorf("expected operator")
+	}
+	p.skipSpaces()
+	valueStart := p.pos
+	value, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	pred.value = value
+	p.skipSpaces()
+	if p.done() || p.peek() != ']' {
+		return nil, p.errorf("expected ]")
+	}
+	p.pos++
+	if pred.operator == "~=" {
+		if pred.re, err = regexp.Compile(pred.value); err != nil {
+			p.pos = valueStart
+			return nil, p.errorf("%v", err)
+		}
+	}
+	return pred, nil
+}
+
+// parseValue Reads a quoted value or a value up to the closing bracket.
+func (p *queryParser) parseValue() (string, error) {
+	if p.done() {
+		return "", p.errorf("expected value")
+	}
+	if quote := p.peek(); quote == '"' || quote == '\'' {
+		p.pos++
+		start := p.pos
+		for !p.done() && p.peek() != quote {
+			p.pos++
+		}
+		if p.done() {
+			return "", p.errorf("unterminated string")
+		}
+		value := string(p.src[start:p.pos])
+		p.pos++
+		return value, nil
+	}
+	start := p.pos
+	for !p.done() && p.peek() != ']' {
+		p.pos++
+	}
+	return strings.TrimSpace(string(p.src[start:p.pos])), nil
+}
@@ -0,0 +1,78 @@
+package AstUtils
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestQuerySearch(t *testing.T) {
+	src := "package p\n\ntype User struct {\n" +
+		"\tName string `json:\"name\"`\n" +
+		"\tAge  int\n" +
+		"\tAddr struct {\n\t\tStreet string `json:\"street\"`\n\t}\n" +
+		"}\n\ntype Other struct {\n\tID int `json:\"id\"`\n}\n"
+	file, err := parser.ParseFile(token.NewFileSet(), "test.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{query: "TypeSpec[Name=User] > StructType Field[Tag*=json]", want: []string{"Name", "Street"}},
+		{query: "TypeSpec[Name=User] > StructType > FieldList > Field", want: []string{"Name", "Age", "Addr"}},
+		{query: "TypeSpec[Name!=User] Field", want: []string{"ID"}},
+		{query: "Field[Tag.json=id]", want: []string{"ID"}},
+		{query: "Field[Tag]", want: []string{"Name", "Street", "ID"}},
+		{query: "Field[Type=int]", want: []string{"Age", "ID"}},
+		{query: "Field[Name~='^A']", want: []string{"Age", "Addr"}},
+		{query: "StructType > FieldList > *[Name$=e]", want: []string{"Name", "Age"}},
+		{query: "GenDecl[Kind=type] TypeSpec[Name^=O]", want: []string{"Other"}},
+		{query: "BasicLit[Kind=STRING]", want: []string{"`json:\"name\"`", "`json:\"street\"`", "`json:\"id\"`"}},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			query, err := CompileQuery(test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, found := range query.Search(file) {
+				got = append(got, NodeName(*found.Node))
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("found %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCompileQueryErrors(t *testing.T) {
+	for _, src := range []string{
+		"",
+		">",
+		"Field >",
+		"StructType > > Field",
+		"Field[",
+		"Field]",
+		"Field[Foo=1]",
+		"Field[Name 1]",
+		`Field[Name="x]`,
+		"Field[Name~=(]",
+		"Field[Name=x",
+		"[Name=x]",
+		"Feild[Tag*=json]",
+		"TypeSpec > Struct",
+	} {
+		_, err := CompileQuery(src)
+		if err == nil {
+			t.Errorf("query %q compiled, want an error", src)
+		} else if src != "" && !strings.HasPrefix(err.Error(), fmt.Sprintf("query %q at ", src)) {
+			t.Errorf("error %q doesn't report the query and offset", err)
+		}
+	}
+}
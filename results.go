@@ -0,0 +1,137 @@
+package AstUtils
+
+import (
+	"cmp"
+	"go/ast"
+	"slices"
+)
+
+// Result is implemented by the match types of the searches, *FoundNodes, *FileMatch and *TypedMatch, so the
+// functions working on search results can be used with all of them.
+type Result interface {
+	foundNodes() *FoundNodes
+}
+
+func (f *FoundNodes) foundNodes() *FoundNodes {
+	return f
+}
+
+// Group holds the matches sharing the same enclosing node, see GroupByDecl and GroupByFile.
+type Group[M Result] struct {
+	Node    ast.Node
+	Matches []M
+}
+
+// Dedup Returns the matches without duplicates, keeping the first match of every node. Matches are duplicates if they
+// hold the same node, e.g. when the results of several searches are combined.
+func Dedup[M Result](matches []M) []M {
+	seen := map[ast.Node]bool{}
+	var result []M
+	for _, match := range matches {
+		node := *match.foundNodes().Node
+		if seen[node] {
+			continue
+		}
+		seen[node] = true
+		result = append(result, match)
+	}
+	return result
+}
+
+// SortByPosition Sorts the matches by the position of their nodes. Nodes starting at the same position are sorted
+// outermost first, which is the traversal order. Positions of different files are compared as token.Pos, so files
+// are ordered the way they were added to their FileSet.
+func SortByPosition[M Result](matches []M) {
+	slices.SortStableFunc(matches, func(a, b M) int {
+		nodeA, nodeB := *a.foundNodes().Node, *b.foundNodes().Node
+		if nodeA.Pos() != nodeB.Pos() {
+			return cmp.Compare(nodeA.Pos(), nodeB.Pos())
+		}
+		return cmp.Compare(nodeB.End(), nodeA.End())
+	})
+}
+
+// GroupByDecl Groups the matches by their outermost enclosing declaration, which is the top level declaration for
+// searches started at a file. Matches that are declarations themselves form the group of the declaration. Matches
+// outside of any declaration, like the package name, are grouped with a nil Node. Groups are ordered by their first
+// match.
+func GroupByDecl[M Result](matches []M) []*Group[M] {
+	return groupBy(matches, func(found *FoundNodes) ast.Node {
+		for _, parent := range found.Path.Parents() {
+			if decl, ok := parent.(ast.Decl); ok {
+				return decl
+			}
+		}
+		if decl, ok := (*found.Node).(ast.Decl); ok {
+			return decl
+		}
+		return nil
+	})
+}
+
+// GroupByFile Groups the matches by the *ast.File the search was started at. Matches of searches started at other
+// nodes are grouped with a nil Node. Groups are ordered by their first match.
+func GroupByFile[M Result](matches []M) []*Group[M] {
+	return groupBy(matches, func(found *FoundNodes) ast.Node {
+		root := *found.Node
+		if len(found.Path) > 0 {
+			root = found.Path[0].Parent
+		}
+		if file, ok := root.(*ast.File); ok {
+			return file
+		}
+		return nil
+	})
+}
+
+// groupBy Groups the matches by the node key returns for them, keeping the order of the first match of every group.
+func groupBy[M Result](matches []M, key func(found *FoundNodes) ast.Node) []*Group[M] {
+	var groups []*Group[M]
+	byNode := map[ast.Node]*Group[M]{}
+	for _, match := range matches {
+		node := key(match.foundNodes())
+		group, ok := byNode[node]
+		if !ok {
+			group = &Group[M]{Node: node}
+			byNode[node] = group
+			groups = append(groups, group)
+		}
+		group.Matches = append(group.Matches, match)
+	}
+	return groups
+}
+
+// OutermostOnly Returns the matches, that aren't nested inside the node of another match. E.g. for a search of
+// function literals only the outermost literals are kept, not the ones declared inside of them.
+func OutermostOnly[M Result](matches []M) []M {
+	matched := map[ast.Node]bool{}
+	for _, match := range matches {
+		matched[*match.foundNodes().Node] = true
+	}
+	var result []M
+	for _, match := range matches {
+		if !slices.ContainsFunc(match.foundNodes().Parents, func(parent *ast.Node) bool {
+			return matched[*parent]
+		}) {
+			result = append(result, match)
+		}
+	}
+	return result
+}
+
+// InnermostOnly Returns the matches, whose node doesn't contain the node of another match.
+func InnermostOnly[M Result](matches []M) []M {
+	ancestors := map[ast.Node]bool{}
+	for _, match := range matches {
+		for _, parent := range match.foundNodes().Parents {
+			ancestors[*parent] = true
+		}
+	}
+	var result []M
+	for _, match := range matches {
+		if !ancestors[*match.foundNodes().Node] {
+			result = append(result, match)
+		}
+	}
+	return result
+}
@@ -0,0 +1,208 @@
+package AstUtils
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"slices"
+	"testing"
+)
+
+const resultsSrc = `package p
+
+func a() {
+	f := func() {
+		g := func() {}
+		_ = g
+	}
+	_ = f
+}
+
+var h = func() {}
+
+func b() {}
+`
+
+// searchResults Parses resultsSrc and returns the file together with the matches of the queries, one query after the
+// other.
+func searchResults(t *testing.T, queries ...string) (*ast.File, []*FoundNodes) {
+	t.Helper()
+	file, err := parser.ParseFile(token.NewFileSet(), "results.go", resultsSrc, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var matches []*FoundNodes
+	for _, query := range queries {
+		matches = append(matches, MustCompileQuery(query).Search(file)...)
+	}
+	return file, matches
+}
+
+// positions Returns the lines of the matched nodes, e.g. to compare the matches with the expected ones.
+func positions[M Result](file *ast.File, matches []M) []int {
+	lines := make([]int, len(matches))
+	for i, match := range matches {
+		lines[i] = lineOf(file, *match.foundNodes().Node)
+	}
+	return lines
+}
+
+// lineOf Returns the line of node in resultsSrc, counting from 1.
+func lineOf(file *ast.File, node ast.Node) int {
+	line := 1
+	for _, c := range resultsSrc[:node.Pos()-file.FileStart] {
+		if c == '\n' {
+			line++
+		}
+	}
+	return line
+}
+
+func TestDedup(t *testing.T) {
+	file, funcLits := searchResults(t, "FuncLit")
+	nested := MustCompileQuery("FuncLit FuncLit").Search(file)
+	// equal nodes of another tree are different nodes
+	_, other := searchResults(t, "FuncLit FuncLit")
+
+	tests := []struct {
+		name    string
+		matches []*FoundNodes
+		want    []int
+	}{
+		{name: "empty"},
+		{name: "no duplicates", matches: funcLits, want: []int{4, 5, 11}},
+		{name: "combined searches", matches: slices.Concat(nested, funcLits), want: []int{5, 4, 11}},
+		{name: "same search twice", matches: slices.Concat(funcLits, funcLits), want: []int{4, 5, 11}},
+	}
+	for _, test := range tests {
+		got := Dedup(test.matches)
+		if lines := positions(file, got); !slices.Equal(lines, test.want) {
+			t.Errorf("%s: kept matches at lines %v, want %v", test.name, lines, test.want)
+		}
+	}
+	if got := Dedup(slices.Concat(funcLits, nested)); got[1] != funcLits[1] {
+		t.Error("Dedup didn't keep the first match of a node")
+	}
+	if len(Dedup(slices.Concat(funcLits, other))) != 4 {
+		t.Error("Dedup merged matches of equal nodes of different trees")
+	}
+}
+
+func TestSortByPosition(t *testing.T) {
+	file, matches := searchResults(t, "FuncLit", "FuncDecl", "FuncType")
+	slices.Reverse(matches)
+	SortByPosition(matches)
+
+	var got []string
+	for _, match := range matches {
+		got = append(got, fmt.Sprintf("%T", *match.Node))
+	}
+	// a FuncLit starts at the same position as its FuncType, the outer node comes first
+	want := []string{
+		"*ast.FuncDecl", "*ast.FuncType",
+		"*ast.FuncLit", "*ast.FuncType",
+		"*ast.FuncLit", "*ast.FuncType",
+		"*ast.FuncLit", "*ast.FuncType",
+		"*ast.FuncDecl", "*ast.FuncType",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("sorted to %v, want %v", got, want)
+	}
+	if lines := positions(file, matches); !slices.IsSorted(lines) {
+		t.Errorf("matches not sorted by line: %v", lines)
+	}
+}
+
+func TestGroupByDecl(t *testing.T) {
+	file, matches := searchResults(t, "File > Ident", "FuncLit", "FuncDecl[Name=b]")
+	groups := GroupByDecl(matches)
+
+	want := []struct {
+		decl  ast.Node
+		lines []int
+	}{
+		{decl: nil, lines: []int{1}},
+		{decl: file.Decls[0], lines: []int{4, 5}},
+		{decl: file.Decls[1], lines: []int{11}},
+		{decl: file.Decls[2], lines: []int{13}},
+	}
+	if len(groups) != len(want) {
+		t.Fatalf("got %d groups, want %d", len(groups), len(want))
+	}
+	for i, group := range groups {
+		if group.Node != want[i].decl {
+			t.Errorf("group %d is of %T, want %T", i, group.Node, want[i].decl)
+		}
+		if lines := positions(file, group.Matches); !slices.Equal(lines, want[i].lines) {
+			t.Errorf("group %d holds matches at lines %v, want %v", i, lines, want[i].lines)
+		}
+	}
+}
+
+func TestGroupByFile(t *testing.T) {
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, src := range []string{"package p\n\nfunc a() {}\n", "package p\n\nfunc b() {}\n\nfunc c() {}\n"} {
+		file, err := parser.ParseFile(fset, "", src, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, file)
+	}
+	pkg, err := NewPackage(fset, files...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	matches := pkg.Search(func(node ast.Node, path Path) bool {
+		_, ok := node.(*ast.FuncDecl)
+		return ok
+	})
+	// a search started below a file is grouped with a nil Node
+	body := MustCompileQuery("BlockStmt").Search(files[0].Decls[0])
+	matches = append(matches, &FileMatch{FoundNodes: body[0]})
+
+	groups := GroupByFile(matches)
+	want := []struct {
+		file  ast.Node
+		count int
+	}{
+		{file: files[0], count: 1},
+		{file: files[1], count: 2},
+		{file: nil, count: 1},
+	}
+	if len(groups) != len(want) {
+		t.Fatalf("got %d groups, want %d", len(groups), len(want))
+	}
+	for i, group := range groups {
+		if group.Node != want[i].file || len(group.Matches) != want[i].count {
+			t.Errorf("group %d is of %T with %d matches, want %d", i, group.Node, len(group.Matches), want[i].count)
+		}
+	}
+	fileMatch := MustCompileQuery("File").Search(files[1])
+	if groups := GroupByFile(fileMatch); len(groups) != 1 || groups[0].Node != files[1] {
+		t.Error("the file itself isn't grouped with its file")
+	}
+}
+
+func TestOutermostInnermostOnly(t *testing.T) {
+	tests := []struct {
+		queries   []string
+		outermost []int
+		innermost []int
+	}{
+		{queries: []string{"FuncLit"}, outermost: []int{4, 11}, innermost: []int{5, 11}},
+		{queries: []string{"FuncDecl", "FuncLit"}, outermost: []int{3, 13, 11}, innermost: []int{13, 5, 11}},
+		{queries: []string{"FuncDecl[Name=b]"}, outermost: []int{13}, innermost: []int{13}},
+		{queries: []string{"ReturnStmt"}},
+	}
+	for _, test := range tests {
+		file, matches := searchResults(t, test.queries...)
+		if lines := positions(file, OutermostOnly(matches)); !slices.Equal(lines, test.outermost) {
+			t.Errorf("%v: outermost matches at lines %v, want %v", test.queries, lines, test.outermost)
+		}
+		if lines := positions(file, InnermostOnly(matches)); !slices.Equal(lines, test.innermost) {
+			t.Errorf("%v: innermost matches at lines %v, want %v", test.queries, lines, test.innermost)
+		}
+	}
+}
@@ -0,0 +1,495 @@
+package AstUtils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// RewriteRule rewrites every occurrence of the From pattern into the To template, like gofmt -r. Both are compiled
+// with CompilePattern. Metavariables bound by From are substituted into To, $*name metavariables are spliced into the
+// surrounding list. If From consists of statements, To may consist of any number of statements. An expression From
+// rewritten into several statements only matches expression statements, e.g. g($*a) rewritten into h($*a); h2(). A
+// rewrite is only applied if all conditions hold.
+type RewriteRule struct {
+	From  string
+	To    string
+	Where []Condition
+}
+
+// Condition decides whether a match of a RewriteRule gets rewritten, based on the bound metavariables.
+type Condition func(bindings Bindings) bool
+
+// AppliedRewrite reports a single rewrite. Path is the path of the first replaced node. Before holds the replaced
+// nodes, After the nodes they got replaced with.
+type AppliedRewrite struct {
+	From     string
+	To       string
+	Path     Path
+	Before   []ast.Node
+	After    []ast.Node
+	Bindings Bindings
+}
+
+// Rewrite Rewrites every match of the from pattern inside file into the to template, see RewriteRule. Returns a
+// report of every applied rewrite.
+func Rewrite(file *ast.File, from, to string, conditions ...Condition) ([]*AppliedRewrite, error) {
+	rule := &RewriteRule{
+		From:  from,
+		To:    to,
+		Where: conditions,
+	}
+	return rule.Apply(context.Background(), file)
+}
+
+// Apply Applies the rule to root. Matches are rewritten bottom-up, so a match containing an already rewritten match
+// is matched against the rewritten nodes. Matches whose replacement doesn't fit at their place, like a call replacing
+// the selected name of a selector, are skipped. Returns an error before rewriting anything, if To uses a metavariable
+// From doesn't bind. Rewrites applied before an error occurred or ctx got done are kept and reported.
+func (r *RewriteRule) Apply(ctx context.Context, root ast.Node) ([]*AppliedRewrite, error) {
+	from, err := CompilePattern(r.From)
+	if err != nil {
+		return nil, fmt.Errorf("from: %w", err)
+	}
+	to, err := CompilePattern(r.To)
+	if err != nil {
+		return nil, fmt.Errorf("to: %w", err)
+	}
+	bound := metavariables(from.nodes)
+	delete(bound, "_")
+	for name := range metavariables(to.nodes) {
+		if !bound[name] {
+			return nil, fmt.Errorf("to: metavariable %s isn't bound by from", name)
+		}
+	}
+	if to.stmts && !from.stmts {
+		// rewrite the statement as part of the list holding it, as the length of the list changes. An expression is
+		// matched as expression statement.
+		stmt := from.nodes[0]
+		if expr, ok := stmt.(ast.Expr); ok {
+			stmt = &ast.ExprStmt{X: expr}
+		}
+		if _, ok := stmt.(ast.Stmt); !ok {
+			return nil, errors.New("a declaration can't be rewritten into multiple statements")
+		}
+		from = &Pattern{
+			src:   from.src,
+			nodes: []ast.Node{stmt},
+			stmts: true,
+		}
+	}
+
+	var applied []*AppliedRewrite
+	var rewriteErr error
+	err = VisitNodesContext(ctx, root, VisitorFuncs{
+		EnterFunc: func(node ast.Node, path Path) Action {
+			if rewriteErr != nil {
+				return Stop
+			}
+			return Continue
+		},
+		LeaveFunc: func(node ast.Node, path Path) {
+			if rewriteErr != nil {
+				return
+			}
+			var rewrites []*AppliedRewrite
+			if from.stmts {
+				rewrites, rewriteErr = r.rewriteList(from, to, node, path)
+			} else if len(path) > 0 {
+				rewrites, rewriteErr = r.rewriteNode(from, to, node, path)
+			}
+			applied = append(applied, rewrites...)
+		},
+	})
+	if rewriteErr != nil {
+		return applied, rewriteErr
+	}
+	return applied, err
+}
+
+// rewriteNode Rewrites node, if it matches the pattern.
+func (r *RewriteRule) rewriteNode(from, to *Pattern, node ast.Node, path Path) ([]*AppliedRewrite, error) {
+	bindings, ok := from.Match(node)
+	if !ok || !r.holds(bindings) {
+		return nil, nil
+	}
+	replacement, err := instantiate(to.nodes, bindings, node.Pos())
+	if err != nil {
+		// the bound nodes don't fit into the template
+		return nil, nil
+	}
+	if len(replacement) != 1 {
+		return nil, fmt.Errorf("template %s doesn't produce a single node", r.To)
+	}
+	slot, _, err := slotOf(path)
+	if err != nil {
+		return nil, err
+	}
+	value, err := adaptNode(replacement[0], slot.Type())
+	if err != nil {
+		// the replacement doesn't fit at the place of the match, like a call in place of a selected name
+		return nil, nil
+	}
+	slot.Set(value)
+	return []*AppliedRewrite{{
+		From:     r.From,
+		To:       r.To,
+		Path:     path.Clone(),
+		Before:   []ast.Node{node},
+		After:    []ast.Node{value.Interface().(ast.Node)},
+		Bindings: bindings,
+	}}, nil
+}
+
+// rewriteList Rewrites all non overlapping matches of a statement pattern in the statement list of node.
+func (r *RewriteRule) rewriteList(from, to *Pattern, node ast.Node, path Path) ([]*AppliedRewrite, error) {
+	list, field := stmtList(node)
+	if list == nil {
+		return nil, nil
+	}
+	var applied []*AppliedRewrite
+	var result []ast.Stmt
+	for start := 0; start < len(list); start++ {
+		matched := false
+		for end := len(list); end > start; end-- {
+			m := &patternMatcher{bindings: Bindings{}}
+			if !m.matchList(from.nodes, list[start:end]) || !r.holds(m.bindings) {
+				continue
+			}
+			after, ok := instantiateStmts(to, m.bindings, list[start].Pos())
+			if !ok {
+				continue
+			}
+			for _, n := range after {
+				result = append(result, n.(ast.Stmt))
+			}
+			applied = append(applied, &AppliedRewrite{
+				From: r.From,
+				To:   r.To,
+				Path: append(path[:len(path):len(path)], PathStep{
+					Parent: node,
+					Field:  field,
+					Index:  len(result) - len(after),
+				}),
+				Before:   list[start:end],
+				After:    after,
+				Bindings: m.bindings,
+			})
+			start = end - 1
+			matched = true
+			break
+		}
+		if !matched {
+			result = append(result, list[start].(ast.Stmt))
+		}
+	}
+	if len(applied) > 0 {
+		reflect.ValueOf(node).Elem().FieldByName(field).Set(reflect.ValueOf(result))
+	}
+	return applied, nil
+}
+
+// instantiateStmts Instantiates the template as statements. Returns false, if the bound nodes don't fit into the
+// template or the result isn't a statement.
+func instantiateStmts(to *Pattern, bindings Bindings, pos token.Pos) ([]ast.Node, bool) {
+	replacement, err := instantiate(to.nodes, bindings, pos)
+	if err != nil {
+		return nil, false
+	}
+	stmts := make([]ast.Node, len(replacement))
+	for i, n := range replacement {
+		value, err := adaptNode(n, stmtType)
+		if err != nil {
+			return nil, false
+		}
+		stmts[i] = value.Interface().(ast.Node)
+	}
+	return stmts, true
+}
+
+// metavariables Returns the names of the metavariables used in nodes.
+func metavariables(nodes []ast.Node) map[string]bool {
+	names := map[string]bool{}
+	for _, node := range nodes {
+		ast.Inspect(node, func(n ast.Node) bool {
+			if ident, ok := n.(*ast.Ident); ok {
+				if name, _, isMeta := metaName(ident); isMeta {
+					names[name] = true
+				}
+			}
+			return true
+		})
+	}
+	return names
+}
+
+// holds Reports whether all conditions of the rule hold.
+func (r *RewriteRule) holds(bindings Bindings) bool {
+	for _, condition := range r.Where {
+		if !condition(bindings) {
+			return false
+		}
+	}
+	return true
+}
+
+// NameMatches Holds if the metavariable is bound and the names of all bound nodes match the regular expression. See
+// NodeName for the name of a node. Panics if expr can't be compiled.
+func NameMatches(metavariable, expr string) Condition {
+	re := regexp.MustCompile(expr)
+	return func(bindings Bindings) bool {
+		b, ok := bindings[metavariable]
+		if !ok {
+			return false
+		}
+		for _, n := range b.Nodes {
+			if !re.MatchString(NodeName(n)) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// IsKind Holds if the metavariable is bound and all bound nodes are of one of the given kinds. Kinds are the names
+// of the node types without package, e.g. BasicLit or CallExpr.
+func IsKind(metavariable string, kinds ...string) Condition {
+	return func(bindings Bindings) bool {
+		b, ok := bindings[metavariable]
+		if !ok {
+			return false
+		}
+		for _, n := range b.Nodes {
+			found := false
+			for _, kind := range kinds {
+				if NodeKind(n) == kind {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// NodeKind Returns the name of the type of node without package, e.g. CallExpr.
+func NodeKind(node ast.Node) string {
+	t := reflect.TypeOf(node)
+	if t == nil {
+		return ""
+	}
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Name()
+}
+
+// NodeName Returns the name of a node. That's the name of identifiers and named declarations, the selected name of
+// selector expressions, the value of basic literals and the path of imports. Fields and value specs return their
+// names joined by a comma. Returns an empty string for all other nodes.
+func NodeName(node ast.Node) string {
+	switch n := node.(type) {
+	case *ast.Ident:
+		return n.Name
+	case *ast.SelectorExpr:
+		return n.Sel.Name
+	case *ast.BasicLit:
+		return n.Value
+	case *ast.ImportSpec:
+		return strings.Trim(n.Path.Value, "\"`")
+	case *ast.TypeSpec:
+		return n.Name.Name
+	case *ast.FuncDecl:
+		return n.Name.Name
+	case *ast.Field:
+		return joinNames(n.Names)
+	case *ast.ValueSpec:
+		return joinNames(n.Names)
+	}
+	return ""
+}
+
+func joinNames(idents []*ast.Ident) string {
+	names := make([]string, len(idents))
+	for i, ident := range idents {
+		names[i] = ident.Name
+	}
+	return strings.Join(names, ",")
+}
+
+var (
+	stmtType = reflect.TypeOf((*ast.Stmt)(nil)).Elem()
+	exprType = reflect.TypeOf((*ast.Expr)(nil)).Elem()
+)
+
+// instantiate Copies the template nodes, substituting the metavariables with copies of their bound nodes. Positions
+// of the template are set to pos.
+func instantiate(template []ast.Node, bindings Bindings, pos token.Pos) ([]ast.Node, error) {
+	var nodes []ast.Node
+	for _, n := range template {
+		if name, _, ok := metaName(n); ok {
+			b, bound := bindings[name]
+			if !bound {
+				return nil, fmt.Errorf("metavariable %s isn't bound", name)
+			}
+			for _, boundNode := range b.Nodes {
+				nodes = append(nodes, copyNode(boundNode))
+			}
+			continue
+		}
+		v, err := substitute(reflect.ValueOf(n), bindings, pos)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, v.Interface().(ast.Node))
+	}
+	return nodes, nil
+}
+
+// substitute Returns a copy of the template value v, see instantiate.
+func substitute(v reflect.Value, bindings Bindings, pos token.Pos) (reflect.Value, error) {
+	switch v.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		if v.IsNil() {
+			return v, nil
+		}
+		if n, ok := v.Interface().(ast.Node); ok {
+			if name, _, isMeta := metaName(n); isMeta {
+				b, bound := bindings[name]
+				if !bound || len(b.Nodes) != 1 {
+					return reflect.Value{}, fmt.Errorf("metavariable %s isn't bound to a single node", name)
+				}
+				return adaptNode(copyNode(b.Node()), v.Type())
+			}
+		}
+		if v.Kind() == reflect.Interface {
+			return substitute(v.Elem(), bindings, pos)
+		}
+		c := reflect.New(v.Type().Elem())
+		elem, err := substitute(v.Elem(), bindings, pos)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		c.Elem().Set(elem)
+		return c, nil
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.NumField(); i++ {
+			switch v.Type().Field(i).Type {
+			case posType:
+				if v.Field(i).Interface() != token.NoPos {
+					c.Field(i).Set(reflect.ValueOf(pos))
+				}
+				continue
+			case commentGroupType, objectType, scopeType:
+				continue
+			}
+			field, err := substitute(v.Field(i), bindings, pos)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			c.Field(i).Set(field)
+		}
+		return c, nil
+	case reflect.Slice:
+		if v.IsNil() {
+			return v, nil
+		}
+		c := reflect.MakeSlice(v.Type(), 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			elem := v.Index(i)
+			if n, ok := elem.Interface().(ast.Node); ok {
+				if name, isList, isMeta := metaName(n); isMeta && isList {
+					b, bound := bindings[name]
+					if !bound {
+						return reflect.Value{}, fmt.Errorf("metavariable %s isn't bound", name)
+					}
+					for _, boundNode := range b.Nodes {
+						value, err := adaptNode(copyNode(boundNode), v.Type().Elem())
+						if err != nil {
+							return reflect.Value{}, err
+						}
+						c = reflect.Append(c, value)
+					}
+					continue
+				}
+			}
+			value, err := substitute(elem, bindings, pos)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			c = reflect.Append(c, value)
+		}
+		return c, nil
+	}
+	return v, nil
+}
+
+// adaptNode Converts node into a value of type t. Expressions are wrapped into expression statements and expression
+// statements are unwrapped, if needed.
+func adaptNode(node ast.Node, t reflect.Type) (reflect.Value, error) {
+	v := reflect.ValueOf(node)
+	if v.Type().AssignableTo(t) {
+		return v, nil
+	}
+	if expr, ok := node.(ast.Expr); ok && t == stmtType {
+		return reflect.ValueOf(&ast.ExprStmt{X: expr}), nil
+	}
+	if stmt, ok := node.(*ast.ExprStmt); ok && exprType.AssignableTo(t) {
+		return adaptNode(stmt.X, t)
+	}
+	return reflect.Value{}, fmt.Errorf("can't use %T as %s", node, t)
+}
+
+// copyNode Returns a deep copy of node, keeping positions but dropping comments and object resolution.
+func copyNode(node ast.Node) ast.Node {
+	v := deepCopy(reflect.ValueOf(node))
+	return v.Interface().(ast.Node)
+}
+
+func deepCopy(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(deepCopy(v.Elem()))
+		return c
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		switch v.Type() {
+		case commentGroupType, objectType, scopeType:
+			return reflect.Zero(v.Type())
+		}
+		c := reflect.New(v.Type().Elem())
+		c.Elem().Set(deepCopy(v.Elem()))
+		return c
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.NumField(); i++ {
+			c.Field(i).Set(deepCopy(v.Field(i)))
+		}
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return c
+	}
+	return v
+}
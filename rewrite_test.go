@@ -0,0 +1,155 @@
+package AstUtils
+
+import (
+	"bytes"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+func TestRewrite(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		from, to   string
+		conditions []Condition
+		want       string
+		// rewrites is the number of applied rewrites
+		rewrites int
+		wantErr  bool
+	}{
+		{
+			name:     "swap arguments",
+			body:     "_ = errors.Wrap(err, \"read\")",
+			from:     "errors.Wrap($err, $msg)",
+			to:       "fmt.Errorf($msg+\": %w\", $err)",
+			want:     "_ = fmt.Errorf(\"read\"+\": %w\", err)",
+			rewrites: 1,
+		},
+		{
+			name:     "list metavariable",
+			body:     "log.Printf(\"%d %d\", a, b)",
+			from:     "log.Printf($format, $*args)",
+			to:       "slog.Info(fmt.Sprintf($format, $*args))",
+			want:     "slog.Info(fmt.Sprintf(\"%d %d\", a, b))",
+			rewrites: 1,
+		},
+		{
+			name:     "repeated metavariable",
+			body:     "_ = a == a\n\t_ = a == b",
+			from:     "$x == $x",
+			to:       "true",
+			want:     "_ = true\n\t_ = a == b",
+			rewrites: 1,
+		},
+		{
+			name:       "name condition",
+			body:       "getA()\n\tsetA()",
+			from:       "$f()",
+			to:         "$f(ctx)",
+			conditions: []Condition{NameMatches("f", "^get")},
+			want:       "getA(ctx)\n\tsetA()",
+			rewrites:   1,
+		},
+		{
+			name:       "kind condition",
+			body:       "g(1)\n\tg(x)",
+			from:       "g($a)",
+			to:         "h($a)",
+			conditions: []Condition{IsKind("a", "BasicLit")},
+			want:       "h(1)\n\tg(x)",
+			rewrites:   1,
+		},
+		{
+			name:     "statements",
+			body:     "if err != nil {\n\t\treturn err\n\t}\n\tif err != nil {\n\t\treturn nil\n\t}",
+			from:     "if $err != nil { return $err }",
+			to:       "check($err)",
+			want:     "check(err)",
+			rewrites: 1,
+		},
+		{
+			name:     "expression into statements",
+			body:     "g(1, 2)\nx := g(3)",
+			from:     "g($*a)",
+			to:       "h($*a); h2()",
+			want:     "h(1, 2)\n\th2()\n\tx := g(3)",
+			rewrites: 1,
+		},
+		{
+			name:    "declaration into statements",
+			body:    "var a = 1",
+			from:    "var $x = $v",
+			to:      "a(); b()",
+			wantErr: true,
+		},
+		{
+			name:    "malformed from",
+			body:    "g()",
+			from:    "g(",
+			to:      "h()",
+			wantErr: true,
+		},
+		{
+			name:    "malformed to",
+			body:    "g()",
+			from:    "g()",
+			to:      "h($)",
+			wantErr: true,
+		},
+		{
+			name:     "spread",
+			body:     "f(xs...)\n\tf(x)",
+			from:     "f($x)",
+			to:       "g($x)",
+			want:     "f(xs...)\n\tg(x)",
+			rewrites: 1,
+		},
+		{
+			name:     "replacement not fitting",
+			body:     "_ = foo + x.foo",
+			from:     "foo",
+			to:       "bar()",
+			want:     "_ = bar() + x.foo",
+			rewrites: 1,
+		},
+		{
+			name:    "unbound metavariable",
+			body:    "h()",
+			from:    "g($a)",
+			to:      "h($b)",
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fset := token.NewFileSet()
+			file, err := parser.ParseFile(fset, "test.go", "package p\n\nfunc f() {\n"+test.body+"\n}\n", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			applied, err := Rewrite(file, test.from, test.to, test.conditions...)
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("rewrite succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(applied) != test.rewrites {
+				t.Errorf("applied %d rewrites, want %d", len(applied), test.rewrites)
+			}
+			var buf bytes.Buffer
+			if err := format.Node(&buf, fset, file); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(buf.String(), "\t"+test.want+"\n") {
+				t.Errorf("got\n%s\nwant body\n%s", buf.String(), test.want)
+			}
+		})
+	}
+}
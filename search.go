@@ -1,438 +1,293 @@
 package AstUtils
 
-import "go/ast"
+import (
+	"context"
+	"go/ast"
+)
 
-// FoundNodes holds the information for each found node
+// FoundNodes holds the information for each found node. Parents holds the ancestors of the node, starting with the
+// direct parent, followed by the parents passed to SearchNodes. Path holds the same ancestry in root-to-leaf order,
+// including the field and index of every step. Location is only set if positions were requested, see AddLocations.
 type FoundNodes struct {
-	Node    *ast.Node
-	Parents []*ast.Node
+	Node     *ast.Node
+	Parents  []*ast.Node
+	Path     Path
+	Location *Location
 }
 
 // SearchNodes Searches the Ast-tree. The search function decides what's a match. foundNodes holds all matches including
 // their parents. This allows to modify a method call inside a function, then traverses upwards to modify the containing
 // function parameters as well.
 // Set completed to true inside the search function, if the search should be terminated.
-func SearchNodes(decl ast.Node, foundNodes *[]*FoundNodes, parents []*ast.Node, searchFunction func(node *ast.Node, parents []*ast.Node, completed *bool) bool, completed *bool) {
+// Every call of the search function gets its own node pointer, which stays valid and is the Node of the match. The
+// list of parents passed to it is shared between the nodes and only valid during the call, the parents of the found
+// nodes are copies.
+// The visited nodes and their order are the same ast.Inspect uses, see IncludeComments to visit all comments as well.
+func SearchNodes(decl ast.Node, foundNodes *[]*FoundNodes, parents []*ast.Node, searchFunction func(node *ast.Node, parents []*ast.Node, completed *bool) bool, completed *bool, opts ...SearchOption) {
+	_ = SearchNodesContext(context.Background(), decl, foundNodes, parents, searchFunction, completed, opts...)
+}
+
+// SearchNodesContext Works like SearchNodes, but terminates the search once ctx is done. Matches found until then are
+// kept in foundNodes. Returns ctx.Err() if the search was cancelled.
+func SearchNodesContext(ctx context.Context, decl ast.Node, foundNodes *[]*FoundNodes, parents []*ast.Node, searchFunction func(node *ast.Node, parents []*ast.Node, completed *bool) bool, completed *bool, opts ...SearchOption) error {
 	if completed == nil {
 		b := false
 		completed = &b
 	}
-	if searchFunction(&decl, parents, completed) {
-		*foundNodes = append(*foundNodes, &FoundNodes{
-			Node:    &decl,
-			Parents: parents,
-		})
-	}
 	if *completed {
-		return
+		return nil
 	}
-	parents = append([]*ast.Node{&decl}, parents...)
-	switch decl.(type) {
-	case *ast.Comment:
-		if decl.(*ast.Comment) == nil {
-			return
-		}
-		return
+	stack := newParentStack(parents)
+	_, err := walkPath(ctx, decl, Path{}, func(node ast.Node, path Path) bool {
+		ptr := stack.push(node, len(path))
+		if searchFunction(ptr, stack.parents(len(path)), completed) {
+			*foundNodes = append(*foundNodes, &FoundNodes{
+				Node:    ptr,
+				Parents: parentsOf(path, parents),
+				Path:    path.Clone(),
+			})
+		}
+		return !*completed
+	}, opts)
+	return err
+}
+
+// parentStack holds the nodes and parent lists passed to the search function of SearchNodes. Every visited node gets
+// its own pointer, taken from chunks of nodes, so the pointers stay valid without a variable escaping per node. The
+// parent lists of all nodes share a single buffer of these pointers, which is filled from the end. The list of a node
+// at depth d is the suffix of the buffer starting d entries before the outer parents, so it begins with the direct
+// parent.
+type parentStack struct {
+	chunk []ast.Node
+	buf   []*ast.Node
+	// end is the index of the first outer parent
+	end int
+}
+
+func newParentStack(outerParents []*ast.Node) *parentStack {
+	const initialDepth = 32
+	buf := make([]*ast.Node, initialDepth+len(outerParents))
+	copy(buf[initialDepth:], outerParents)
+	return &parentStack{
+		buf: buf,
+		end: initialDepth,
+	}
+}
+
+// push Returns a new pointer to node, which is at the given depth, and records it as direct parent of the nodes below
+// it. The buffer is grown if needed. Lists returned before keep pointing to the old buffer, which still holds the
+// ancestors of the node.
+func (s *parentStack) push(node ast.Node, depth int) *ast.Node {
+	const chunkSize = 256
+	if len(s.chunk) == cap(s.chunk) {
+		s.chunk = make([]ast.Node, 0, chunkSize)
+	}
+	s.chunk = append(s.chunk, node)
+	ptr := &s.chunk[len(s.chunk)-1]
+	if depth >= s.end {
+		grown := make([]*ast.Node, 2*len(s.buf))
+		offset := len(grown) - len(s.buf)
+		copy(grown[offset:], s.buf)
+		s.buf = grown
+		s.end += offset
+	}
+	s.buf[s.end-1-depth] = ptr
+	return ptr
+}
+
+// parents Returns the parent list of a node at the given depth. It's only valid until the next node is pushed.
+func (s *parentStack) parents(depth int) []*ast.Node {
+	return s.buf[s.end-depth:]
+}
+
+// parentsOf Converts path into the parent list used by SearchNodes, starting with the direct parent and followed by
+// outerParents. The parents are copied into a single slice, their pointers are valid independent of any traversal.
+func parentsOf(path Path, outerParents []*ast.Node) []*ast.Node {
+	nodes := make([]ast.Node, len(path))
+	parents := make([]*ast.Node, 0, len(path)+len(outerParents))
+	for i := len(path) - 1; i >= 0; i-- {
+		nodes[i] = path[i].Parent
+		parents = append(parents, &nodes[i])
+	}
+	return append(parents, outerParents...)
+}
+
+// walkChildren Calls fn for every non nil child of node, together with the name of the field holding the child and
+// its index, if the field is a slice. The index is -1 otherwise. Children and their order are the same ast.Walk uses.
+// Returns false, if fn requested to stop.
+func walkChildren(node ast.Node, fn func(child ast.Node, field string, index int) bool) bool {
+	visit := func(child ast.Node, field string) bool {
+		if isNilNode(child) {
+			return true
+		}
+		return fn(child, field, -1)
+	}
+	switch n := node.(type) {
 	case *ast.CommentGroup:
-		if decl.(*ast.CommentGroup) == nil {
-			return
-		}
-		if decl.(*ast.CommentGroup) == nil {
-			return
-		}
-		return
+		return visitList(n.List, "List", fn)
 	case *ast.Field:
-		if decl.(*ast.Field) == nil {
-			return
-		}
-		parents = append(parents, &decl)
-		SearchNodes(decl.(*ast.Field).Doc, foundNodes, parents, searchFunction, completed)
-		if decl.(*ast.Field).Names != nil {
-			for i := range decl.(*ast.Field).Names {
-				SearchNodes(decl.(*ast.Field).Names[i], foundNodes, parents, searchFunction, completed)
-			}
-		}
-		SearchNodes(decl.(*ast.Field).Type, foundNodes, parents, searchFunction, completed)
-		SearchNodes(decl.(*ast.Field).Tag, foundNodes, parents, searchFunction, completed)
-		SearchNodes(decl.(*ast.Field).Comment, foundNodes, parents, searchFunction, completed)
+		return visit(n.Doc, "Doc") &&
+			visitList(n.Names, "Names", fn) &&
+			visit(n.Type, "Type") &&
+			visit(n.Tag, "Tag") &&
+			visit(n.Comment, "Comment")
 	case *ast.FieldList:
-		if decl.(*ast.FieldList) == nil {
-			return
-		}
-		if decl.(*ast.FieldList).List != nil {
-			for i := range decl.(*ast.FieldList).List {
-				SearchNodes(decl.(*ast.FieldList).List[i], foundNodes, parents, searchFunction, completed)
-			}
-		}
-	case *ast.BadExpr:
-		if decl.(*ast.BadExpr) == nil {
-			return
-		}
-		return
-	case *ast.Ident:
-		if decl.(*ast.Ident) == nil {
-			return
-		}
-		return
+		return visitList(n.List, "List", fn)
 	case *ast.Ellipsis:
-		if decl.(*ast.Ellipsis) == nil {
-			return
-		}
-		SearchNodes(decl.(*ast.Ellipsis).Elt, foundNodes, parents, searchFunction, completed)
-	case *ast.BasicLit:
-		if decl.(*ast.BasicLit) == nil {
-			return
-		}
-		return
+		return visit(n.Elt, "Elt")
 	case *ast.FuncLit:
-		if decl.(*ast.FuncLit) == nil {
-			return
-		}
-		SearchNodes(decl.(*ast.FuncLit).Type, foundNodes, parents, searchFunction, completed)
-		SearchNodes(decl.(*ast.FuncLit).Body, foundNodes, parents, searchFunction, completed)
+		return visit(n.Type, "Type") &&
+			visit(n.Body, "Body")
 	case *ast.CompositeLit:
-		if decl.(*ast.CompositeLit) == nil {
-			return
-		}
-		SearchNodes(decl.(*ast.CompositeLit).Type, foundNodes, parents, searchFunction, completed)
-		if decl.(*ast.CompositeLit).Elts != nil {
-			for i := range decl.(*ast.CompositeLit).Elts {
-				SearchNodes(decl.(*ast.CompositeLit).Elts[i], foundNodes, parents, searchFunction, completed)
-			}
-		}
+		return visit(n.Type, "Type") &&
+			visitList(n.Elts, "Elts", fn)
 	case *ast.ParenExpr:
-		if decl.(*ast.ParenExpr) == nil {
-			return
-		}
-		SearchNodes(decl.(*ast.ParenExpr).X, foundNodes, parents, searchFunction, completed)
+		return visit(n.X, "X")
 	case *ast.SelectorExpr:
-		if decl.(*ast.SelectorExpr) == nil {
-			return
-		}
-		SearchNodes(decl.(*ast.SelectorExpr).X, foundNodes, parents, searchFunction, completed)
-		SearchNodes(decl.(*ast.SelectorExpr).Sel, foundNodes, parents, searchFunction, completed)
+		return visit(n.X, "X") &&
+			visit(n.Sel, "Sel")
 	case *ast.IndexExpr:
-		if decl.(*ast.IndexExpr) == nil {
-			return
-		}
-		SearchNodes(decl.(*ast.IndexExpr).X, foundNodes, parents, searchFunction, completed)
-		SearchNodes(decl.(*ast.IndexExpr).Index, foundNodes, parents, searchFunction, completed)
+		return visit(n.X, "X") &&
+			visit(n.Index, "Index")
 	case *ast.IndexListExpr:
-		if decl.(*ast.IndexListExpr) == nil {
-			return
-		}
-		SearchNodes(decl.(*ast.IndexListExpr).X, foundNodes, parents, searchFunction, completed)
-		if decl.(*ast.IndexListExpr).Indices != nil {
-			for i := range decl.(*ast.IndexListExpr).Indices {
-				SearchNodes(decl.(*ast.IndexListExpr).Indices[i], foundNodes, parents, searchFunction, completed)
-			}
-		}
+		return visit(n.X, "X") &&
+			visitList(n.Indices, "Indices", fn)
 	case *ast.SliceExpr:
-		if decl.(*ast.SliceExpr) == nil {
-			return
-		}
-		SearchNodes(decl.(*ast.SliceExpr).X, foundNodes, parents, searchFunction, completed)
-		SearchNodes(decl.(*ast.SliceExpr).Low, foundNodes, parents, searchFunction, completed)
-		SearchNodes(decl.(*ast.SliceExpr).High, foundNodes, parents, searchFunction, completed)
-		SearchNodes(decl.(*ast.SliceExpr).Max, foundNodes, parents, searchFunction, completed)
+		return visit(n.X, "X") &&
+			visit(n.Low, "Low") &&
+			visit(n.High, "High") &&
+			visit(n.Max, "Max")
 	case *ast.TypeAssertExpr:
-		if decl.(*ast.TypeAssertExpr) == nil {
-			return
-		}
-		SearchNodes(decl.(*ast.TypeAssertExpr).X, foundNodes, parents, searchFunction, completed)
-		SearchNodes(decl.(*ast.TypeAssertExpr).Type, foundNodes, parents, searchFunction, completed)
+		return visit(n.X, "X") &&
+			visit(n.Type, "Type")
 	case *ast.CallExpr:
-		if decl.(*ast.CallExpr) == nil {
-			return
-		}
-		SearchNodes(decl.(*ast.CallExpr).Fun, foundNodes, parents, searchFunction, completed)
-		if decl.(*ast.CallExpr).Args != nil {
-			for i := range decl.(*ast.CallExpr).Args {
-				SearchNodes(decl.(*ast.CallExpr).Args[i], foundNodes, parents, searchFunction, completed)
-			}
-		}
+		return visit(n.Fun, "Fun") &&
+			visitList(n.Args, "Args", fn)
 	case *ast.StarExpr:
-		if decl.(*ast.StarExpr) == nil {
-			return
-		}
-		SearchNodes(decl.(*ast.StarExpr).X, foundNodes, parents, searchFunction, completed)
+		return visit(n.X, "X")
 	case *ast.UnaryExpr:
-		if decl.(*ast.UnaryExpr) == nil {
-			return
-		}
-		SearchNodes(decl.(*ast.UnaryExpr).X, foundNodes, parents, searchFunction, completed)
+		return visit(n.X, "X")
 	case *ast.BinaryExpr:
-		if decl.(*ast.BinaryExpr) == nil {
-			return
-		}
-		SearchNodes(decl.(*ast.BinaryExpr).X, foundNodes, parents, searchFunction, completed)
-		SearchNodes(decl.(*ast.BinaryExpr).Y, foundNodes, parents, searchFunction, completed)
+		return visit(n.X, "X") &&
+			visit(n.Y, "Y")
 	case *ast.KeyValueExpr:
-		if decl.(*ast.KeyValueExpr) == nil {
-			return
-		}
-		SearchNodes(decl.(*ast.KeyValueExpr).Key, foundNodes, parents, searchFunction, completed)
-		SearchNodes(decl.(*ast.KeyValueExpr).Value, foundNodes, parents, searchFunction, completed)
+		return visit(n.Key, "Key") &&
+			visit(n.Value, "Value")
 	case *ast.ArrayType:
-		if decl.(*ast.ArrayType) == nil {
-			return
-		}
-		SearchNodes(decl.(*ast.ArrayType).Elt, foundNodes, parents, searchFunction, completed)
-		SearchNodes(decl.(*ast.ArrayType).Len, foundNodes, parents, searchFunction, completed)
+		return visit(n.Len, "Len") &&
+			visit(n.Elt, "Elt")
 	case *ast.StructType:
-		if decl.(*ast.StructType) == nil {
-			return
-		}
-		SearchNodes(decl.(*ast.StructType).Fields, foundNodes, parents, searchFunction, completed)
+		return visit(n.Fields, "Fields")
 	case *ast.FuncType:
-		if decl.(*ast.FuncType) == nil {
-			return
-		}
-		SearchNodes(decl.(*ast.FuncType).TypeParams, foundNodes, parents, searchFunction, completed)
-		SearchNodes(decl.(*ast.FuncType).Params, foundNodes, parents, searchFunction, completed)
-		SearchNodes(decl.(*ast.FuncType).Results, foundNodes, parents, searchFunction, completed)
+		return visit(n.TypeParams, "TypeParams") &&
+			visit(n.Params, "Params") &&
+			visit(n.Results, "Results")
+	case *ast.InterfaceType:
+		return visit(n.Methods, "Methods")
 	case *ast.MapType:
-		if decl.(*ast.MapType) == nil {
-			return
-		}
-		SearchNodes(decl.(*ast.MapType).Key, foundNodes, parents, searchFunction, completed)
-		SearchNodes(decl.(*ast.MapType).Value, foundNodes, parents, searchFunction, completed)
+		return visit(n.Key, "Key") &&
+			visit(n.Value, "Value")
 	case *ast.ChanType:
-		if decl.(*ast.ChanType) == nil {
-			return
-		}
-		SearchNodes(decl.(*ast.ChanType).Value, foundNodes, parents, searchFunction, completed)
-	case *ast.BadStmt:
-		if decl.(*ast.BadStmt) == nil {
-			return
-		}
-		return
+		return visit(n.Value, "Value")
 	case *ast.DeclStmt:
-		if decl.(*ast.DeclStmt) == nil {
-			return
-		}
-		SearchNodes(decl.(*ast.DeclStmt).Decl, foundNodes, parents, searchFunction, completed)
-	case *ast.EmptyStmt:
-		if decl.(*ast.EmptyStmt) == nil {
-			return
-		}
-		return
+		return visit(n.Decl, "Decl")
 	case *ast.LabeledStmt:
-		if decl.(*ast.LabeledStmt) == nil {
-			return
-		}
-		SearchNodes(decl.(*ast.LabeledStmt).Label, foundNodes, parents, searchFunction, completed)
-		SearchNodes(decl.(*ast.LabeledStmt).Stmt, foundNodes, parents, searchFunction, completed)
+		return visit(n.Label, "Label") &&
+			visit(n.Stmt, "Stmt")
 	case *ast.ExprStmt:
-		if decl.(*ast.ExprStmt) == nil {
-			return
-		}
-		SearchNodes(decl.(*ast.ExprStmt).X, foundNodes, parents, searchFunction, completed)
+		return visit(n.X, "X")
 	case *ast.SendStmt:
-		if decl.(*ast.SendStmt) == nil {
-			return
-		}
-		SearchNodes(decl.(*ast.SendStmt).Chan, foundNodes, parents, searchFunction, completed)
-		SearchNodes(decl.(*ast.SendStmt).Value, foundNodes, parents, searchFunction, completed)
+		return visit(n.Chan, "Chan") &&
+			visit(n.Value, "Value")
 	case *ast.IncDecStmt:
-		if decl.(*ast.IncDecStmt) == nil {
-			return
-		}
-		SearchNodes(decl.(*ast.IncDecStmt).X, foundNodes, parents, searchFunction, completed)
+		return visit(n.X, "X")
 	case *ast.AssignStmt:
-		if decl.(*ast.AssignStmt) == nil {
-			return
-		}
-		if decl.(*ast.AssignStmt).Rhs != nil {
-			for i := range decl.(*ast.AssignStmt).Rhs {
-				SearchNodes(decl.(*ast.AssignStmt).Rhs[i], foundNodes, parents, searchFunction, completed)
-			}
-		}
-		if decl.(*ast.AssignStmt).Lhs != nil {
-			for i := range decl.(*ast.AssignStmt).Lhs {
-				SearchNodes(decl.(*ast.AssignStmt).Lhs[i], foundNodes, parents, searchFunction, completed)
-			}
-		}
+		return visitList(n.Lhs, "Lhs", fn) &&
+			visitList(n.Rhs, "Rhs", fn)
 	case *ast.GoStmt:
-		if decl.(*ast.GoStmt) == nil {
-			return
-		}
-		SearchNodes(decl.(*ast.GoStmt).Call, foundNodes, parents, searchFunction, completed)
+		return visit(n.Call, "Call")
 	case *ast.DeferStmt:
-		if decl.(*ast.DeferStmt) == nil {
-			return
-		}
-		SearchNodes(decl.(*ast.DeferStmt).Call, foundNodes, parents, searchFunction, completed)
+		return visit(n.Call, "Call")
 	case *ast.ReturnStmt:
-		if decl.(*ast.ReturnStmt) == nil {
-			return
-		}
-		if decl.(*ast.ReturnStmt).Results != nil {
-			for i := range decl.(*ast.ReturnStmt).Results {
-				SearchNodes(decl.(*ast.ReturnStmt).Results[i], foundNodes, parents, searchFunction, completed)
-			}
-		}
+		return visitList(n.Results, "Results", fn)
 	case *ast.BranchStmt:
-		if decl.(*ast.BranchStmt) == nil {
-			return
-		}
-		SearchNodes(decl.(*ast.BranchStmt).Label, foundNodes, parents, searchFunction, completed)
+		return visit(n.Label, "Label")
 	case *ast.BlockStmt:
-		if decl.(*ast.BlockStmt) == nil {
-			return
-		}
-		if decl.(*ast.BlockStmt).List != nil {
-			for i := range decl.(*ast.BlockStmt).List {
-				SearchNodes(decl.(*ast.BlockStmt).List[i], foundNodes, parents, searchFunction, completed)
-			}
-		}
+		return visitList(n.List, "List", fn)
 	case *ast.IfStmt:
-		SearchNodes(decl.(*ast.IfStmt).Init, foundNodes, parents, searchFunction, completed)
-		SearchNodes(decl.(*ast.IfStmt).Cond, foundNodes, parents, searchFunction, completed)
-		SearchNodes(decl.(*ast.IfStmt).Body, foundNodes, parents, searchFunction, completed)
-		SearchNodes(decl.(*ast.IfStmt).Else, foundNodes, parents, searchFunction, completed)
+		return visit(n.Init, "Init") &&
+			visit(n.Cond, "Cond") &&
+			visit(n.Body, "Body") &&
+			visit(n.Else, "Else")
 	case *ast.CaseClause:
-		if decl.(*ast.CaseClause) == nil {
-			return
-		}
-		if decl.(*ast.CaseClause).List != nil {
-			for i := range decl.(*ast.CaseClause).List {
-				SearchNodes(decl.(*ast.CaseClause).List[i], foundNodes, parents, searchFunction, completed)
-			}
-		}
-		if decl.(*ast.CaseClause).Body != nil {
-			for i := range decl.(*ast.CaseClause).Body {
-				SearchNodes(decl.(*ast.CaseClause).Body[i], foundNodes, parents, searchFunction, completed)
-			}
-		}
+		return visitList(n.List, "List", fn) &&
+			visitList(n.Body, "Body", fn)
 	case *ast.SwitchStmt:
-		if decl.(*ast.SwitchStmt) == nil {
-			return
-		}
-		SearchNodes(decl.(*ast.SwitchStmt).Init, foundNodes, parents, searchFunction, completed)
-		SearchNodes(decl.(*ast.SwitchStmt).Tag, foundNodes, parents, searchFunction, completed)
-		SearchNodes(decl.(*ast.SwitchStmt).Body, foundNodes, parents, searchFunction, completed)
+		return visit(n.Init, "Init") &&
+			visit(n.Tag, "Tag") &&
+			visit(n.Body, "Body")
 	case *ast.TypeSwitchStmt:
-		if decl.(*ast.TypeSwitchStmt) == nil {
-			return
-		}
-		SearchNodes(decl.(*ast.TypeSwitchStmt).Init, foundNodes, parents, searchFunction, completed)
-		SearchNodes(decl.(*ast.TypeSwitchStmt).Assign, foundNodes, parents, searchFunction, completed)
-		SearchNodes(decl.(*ast.TypeSwitchStmt).Body, foundNodes, parents, searchFunction, completed)
+		return visit(n.Init, "Init") &&
+			visit(n.Assign, "Assign") &&
+			visit(n.Body, "Body")
 	case *ast.CommClause:
-		if decl.(*ast.CommClause) == nil {
-			return
-		}
-		SearchNodes(decl.(*ast.CommClause).Comm, foundNodes, parents, searchFunction, completed)
-		if decl.(*ast.CommClause).Body != nil {
-			for i := range decl.(*ast.CommClause).Body {
-				SearchNodes(decl.(*ast.CommClause).Body[i], foundNodes, parents, searchFunction, completed)
-			}
-		}
+		return visit(n.Comm, "Comm") &&
+			visitList(n.Body, "Body", fn)
 	case *ast.SelectStmt:
-		if decl.(*ast.SelectStmt) == nil {
-			return
-		}
-		SearchNodes(decl.(*ast.SelectStmt).Body, foundNodes, parents, searchFunction, completed)
+		return visit(n.Body, "Body")
 	case *ast.ForStmt:
-		if decl.(*ast.ForStmt) == nil {
-			return
-		}
-		SearchNodes(decl.(*ast.ForStmt).Init, foundNodes, parents, searchFunction, completed)
-		SearchNodes(decl.(*ast.ForStmt).Cond, foundNodes, parents, searchFunction, completed)
-		SearchNodes(decl.(*ast.ForStmt).Post, foundNodes, parents, searchFunction, completed)
-		SearchNodes(decl.(*ast.ForStmt).Body, foundNodes, parents, searchFunction, completed)
+		return visit(n.Init, "Init") &&
+			visit(n.Cond, "Cond") &&
+			visit(n.Post, "Post") &&
+			visit(n.Body, "Body")
 	case *ast.RangeStmt:
-		if decl.(*ast.RangeStmt) == nil {
-			return
-		}
-		SearchNodes(decl.(*ast.RangeStmt).Key, foundNodes, parents, searchFunction, completed)
-		SearchNodes(decl.(*ast.RangeStmt).Value, foundNodes, parents, searchFunction, completed)
-		SearchNodes(decl.(*ast.RangeStmt).X, foundNodes, parents, searchFunction, completed)
-		SearchNodes(decl.(*ast.RangeStmt).Body, foundNodes, parents, searchFunction, completed)
+		return visit(n.Key, "Key") &&
+			visit(n.Value, "Value") &&
+			visit(n.X, "X") &&
+			visit(n.Body, "Body")
 	case *ast.ImportSpec:
-		if decl.(*ast.ImportSpec) == nil {
-			return
-		}
-		SearchNodes(decl.(*ast.ImportSpec).Doc, foundNodes, parents, searchFunction, completed)
-		SearchNodes(decl.(*ast.ImportSpec).Name, foundNodes, parents, searchFunction, completed)
-		SearchNodes(decl.(*ast.ImportSpec).Path, foundNodes, parents, searchFunction, completed)
-		SearchNodes(decl.(*ast.ImportSpec).Comment, foundNodes, parents, searchFunction, completed)
+		return visit(n.Doc, "Doc") &&
+			visit(n.Name, "Name") &&
+			visit(n.Path, "Path") &&
+			visit(n.Comment, "Comment")
 	case *ast.ValueSpec:
-		if decl.(*ast.ValueSpec) == nil {
-			return
-		}
-		SearchNodes(decl.(*ast.ValueSpec).Doc, foundNodes, parents, searchFunction, completed)
-		if decl.(*ast.ValueSpec).Names != nil {
-			for i := range decl.(*ast.ValueSpec).Names {
-				SearchNodes(decl.(*ast.ValueSpec).Names[i], foundNodes, parents, searchFunction, completed)
-			}
-		}
-		SearchNodes(decl.(*ast.ValueSpec).Type, foundNodes, parents, searchFunction, completed)
-		if decl.(*ast.ValueSpec).Values != nil {
-			for i := range decl.(*ast.ValueSpec).Values {
-				SearchNodes(decl.(*ast.ValueSpec).Values[i], foundNodes, parents, searchFunction, completed)
-			}
-		}
-		SearchNodes(decl.(*ast.ValueSpec).Comment, foundNodes, parents, searchFunction, completed)
+		return visit(n.Doc, "Doc") &&
+			visitList(n.Names, "Names", fn) &&
+			visit(n.Type, "Type") &&
+			visitList(n.Values, "Values", fn) &&
+			visit(n.Comment, "Comment")
 	case *ast.TypeSpec:
-		if decl.(*ast.TypeSpec) == nil {
-			return
-		}
-		SearchNodes(decl.(*ast.TypeSpec).Doc, foundNodes, parents, searchFunction, completed)
-		SearchNodes(decl.(*ast.TypeSpec).Name, foundNodes, parents, searchFunction, completed)
-		SearchNodes(decl.(*ast.TypeSpec).TypeParams, foundNodes, parents, searchFunction, completed)
-		SearchNodes(decl.(*ast.TypeSpec).Type, foundNodes, parents, searchFunction, completed)
-		SearchNodes(decl.(*ast.TypeSpec).Comment, foundNodes, parents, searchFunction, completed)
-	case *ast.BadDecl:
-		if decl.(*ast.BadDecl) == nil {
-			return
-		}
-		return
+		return visit(n.Doc, "Doc") &&
+			visit(n.Name, "Name") &&
+			visit(n.TypeParams, "TypeParams") &&
+			visit(n.Type, "Type") &&
+			visit(n.Comment, "Comment")
 	case *ast.GenDecl:
-		if decl.(*ast.GenDecl) == nil {
-			return
-		}
-		SearchNodes(decl.(*ast.GenDecl).Doc, foundNodes, parents, searchFunction, completed)
-		if decl.(*ast.GenDecl).Specs != nil {
-			for i := range decl.(*ast.GenDecl).Specs {
-				SearchNodes(decl.(*ast.GenDecl).Specs[i], foundNodes, parents, searchFunction, completed)
-			}
-		}
+		return visit(n.Doc, "Doc") &&
+			visitList(n.Specs, "Specs", fn)
 	case *ast.FuncDecl:
-		if decl.(*ast.FuncDecl) == nil {
-			return
-		}
-		SearchNodes(decl.(*ast.FuncDecl).Doc, foundNodes, parents, searchFunction, completed)
-		SearchNodes(decl.(*ast.FuncDecl).Recv, foundNodes, parents, searchFunction, completed)
-		SearchNodes(decl.(*ast.FuncDecl).Name, foundNodes, parents, searchFunction, completed)
-		SearchNodes(decl.(*ast.FuncDecl).Type, foundNodes, parents, searchFunction, completed)
-		SearchNodes(decl.(*ast.FuncDecl).Body, foundNodes, parents, searchFunction, completed)
+		return visit(n.Doc, "Doc") &&
+			visit(n.Recv, "Recv") &&
+			visit(n.Name, "Name") &&
+			visit(n.Type, "Type") &&
+			visit(n.Body, "Body")
 	case *ast.File:
-		if decl.(*ast.File) == nil {
-			return
-		}
-		SearchNodes(decl.(*ast.File).Doc, foundNodes, parents, searchFunction, completed)
-		SearchNodes(decl.(*ast.File).Name, foundNodes, parents, searchFunction, completed)
-		if decl.(*ast.File).Decls != nil {
-			for i := range decl.(*ast.File).Decls {
-				SearchNodes(decl.(*ast.File).Decls[i], foundNodes, parents, searchFunction, completed)
-			}
-		}
-		if decl.(*ast.File).Imports != nil {
-			for i := range decl.(*ast.File).Imports {
-				SearchNodes(decl.(*ast.File).Imports[i], foundNodes, parents, searchFunction, completed)
-			}
-		}
-		if decl.(*ast.File).Unresolved != nil {
-			for i := range decl.(*ast.File).Unresolved {
-				SearchNodes(decl.(*ast.File).Unresolved[i], foundNodes, parents, searchFunction, completed)
-			}
+		return visit(n.Doc, "Doc") &&
+			visit(n.Name, "Name") &&
+			visitList(n.Decls, "Decls", fn)
+	}
+	return true
+}
+
+// visitList Calls fn for every non nil element of list, see walkChildren.
+func visitList[T ast.Node](list []T, field string, fn func(child ast.Node, field string, index int) bool) bool {
+	for i, child := range list {
+		if isNilNode(child) {
+			continue
 		}
-		if decl.(*ast.File).Comments != nil {
-			for i := range decl.(*ast.File).Comments {
-				SearchNodes(decl.(*ast.File).Comments[i], foundNodes, parents, searchFunction, completed)
-			}
+		if !fn(child, field, i) {
+			return false
 		}
 	}
+	return true
 }
@@ -0,0 +1,34 @@
+package AstUtils
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+)
+
+func TestSearchNodesKeepsNodePointers(t *testing.T) {
+	file, err := parser.ParseFile(token.NewFileSet(), "test.go", "package p\n\nvar a, b, c = 1, 2, 3\n", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kept []*ast.Node
+	var want []ast.Node
+	var found []*FoundNodes
+	SearchNodes(file, &found, nil, func(node *ast.Node, parents []*ast.Node, completed *bool) bool {
+		kept = append(kept, node)
+		want = append(want, *node)
+		_, ok := (*node).(*ast.Ident)
+		return ok
+	}, nil)
+	for i, node := range kept {
+		if *node != want[i] {
+			t.Fatalf("kept pointer %d reads %T, want %T", i, *node, want[i])
+		}
+	}
+	for _, match := range found {
+		if _, ok := (*match.Node).(*ast.Ident); !ok {
+			t.Fatalf("match reads %T, want *ast.Ident", *match.Node)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package AstUtils
+
+import (
+	"errors"
+	"go/ast"
+	"go/importer"
+	"go/types"
+)
+
+// TypedPackage is a Package that has been type checked with go/types. Info holds the type information of all files.
+type TypedPackage struct {
+	*Package
+	Types *types.Package
+	Info  *types.Info
+}
+
+// TypedMatch is a match of a search across a TypedPackage, carrying the type information of the package.
+type TypedMatch struct {
+	*FileMatch
+	Info *types.Info
+}
+
+// LoadTypedPackage Loads the package in dir, see LoadPackage, and type checks it using the source importer. Test
+// files are skipped.
+func LoadTypedPackage(dir string) (*TypedPackage, error) {
+	pkg, err := LoadPackage(dir, false)
+	if err != nil {
+		return nil, err
+	}
+	return pkg.TypeCheck(nil)
+}
+
+// TypeCheck Type checks the package under its import path, or under its name if the path isn't known. Imports are
+// resolved by imp. A nil imp imports packages from their local sources, nothing is downloaded or read from compiled
+// export data. If type errors occur, the returned TypedPackage holds the type information that could be determined,
+// together with all errors joined.
+func (p *Package) TypeCheck(imp types.Importer) (*TypedPackage, error) {
+	if imp == nil {
+		imp = importer.ForCompiler(p.Fset, "source", nil)
+	}
+	var errs []error
+	config := &types.Config{
+		Importer: imp,
+		Error: func(err error) {
+			errs = append(errs, err)
+		},
+	}
+	info := &types.Info{
+		Types:      map[ast.Expr]types.TypeAndValue{},
+		Defs:       map[*ast.Ident]types.Object{},
+		Uses:       map[*ast.Ident]types.Object{},
+		Implicits:  map[ast.Node]types.Object{},
+		Selections: map[*ast.SelectorExpr]*types.Selection{},
+		Scopes:     map[ast.Node]*types.Scope{},
+	}
+	files := make([]*ast.File, len(p.Files))
+	for i, file := range p.Files {
+		files[i] = file.File
+	}
+	path := p.Path
+	if path == "" {
+		path = p.Name
+	}
+	typesPackage, _ := config.Check(path, p.Fset, files, info)
+	return &TypedPackage{
+		Package: p,
+		Types:   typesPackage,
+		Info:    info,
+	}, errors.Join(errs...)
+}
+
+// SearchTyped Works like Package.Search, but returns the matches together with the type information.
+func (p *TypedPackage) SearchTyped(searchFunction func(node ast.Node, path Path) bool, opts ...SearchOption) []*TypedMatch {
+	var matches []*TypedMatch
+	for _, match := range p.Search(searchFunction, opts...) {
+		matches = append(matches, &TypedMatch{
+			FileMatch: match,
+			Info:      p.Info,
+		})
+	}
+	return matches
+}
+
+// CallsMethod Returns a search function matching calls of the method of the named type typeName declared in the
+// package pkgPath, e.g. CallsMethod("database/sql", "DB", "Query"). Calls through pointers and calls of methods
+// promoted from the type are matched as well.
+func (p *TypedPackage) CallsMethod(pkgPath, typeName, method string) func(node ast.Node, path Path) bool {
+	return func(node ast.Node, path Path) bool {
+		call, ok := node.(*ast.CallExpr)
+		if !ok {
+			return false
+		}
+		selector, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr)
+		if !ok {
+			return false
+		}
+		selection, ok := p.Info.Selections[selector]
+		if !ok || selection.Kind() != types.MethodVal {
+			return false
+		}
+		function, ok := selection.Obj().(*types.Func)
+		if !ok || function.Name() != method {
+			return false
+		}
+		recv := function.Type().(*types.Signature).Recv()
+		if recv == nil {
+			return false
+		}
+		return isNamed(recv.Type(), pkgPath, typeName)
+	}
+}
+
+// CallsFunction Returns a search function matching calls of the package level function name declared in the package
+// pkgPath, e.g. CallsFunction("fmt", "Errorf").
+func (p *TypedPackage) CallsFunction(pkgPath, name string) func(node ast.Node, path Path) bool {
+	return func(node ast.Node, path Path) bool {
+		call, ok := node.(*ast.CallExpr)
+		if !ok {
+			return false
+		}
+		var ident *ast.Ident
+		switch fun := ast.Unparen(call.Fun).(type) {
+		case *ast.Ident:
+			ident = fun
+		case *ast.SelectorExpr:
+			ident = fun.Sel
+		default:
+			return false
+		}
+		function, ok := p.Info.Uses[ident].(*types.Func)
+		if !ok || function.Name() != name || function.Pkg() == nil || function.Pkg().Path() != pkgPath {
+			return false
+		}
+		return function.Type().(*types.Signature).Recv() == nil
+	}
+}
+
+// HasUnderlyingType Returns a search function matching expressions, fields and value specs whose underlying type
+// is typeString, e.g. HasUnderlyingType("int"). The type is compared in the form types.TypeString prints it, so types
+// of other packages are qualified with their full package path.
+func (p *TypedPackage) HasUnderlyingType(typeString string) func(node ast.Node, path Path) bool {
+	return func(node ast.Node, path Path) bool {
+		var t types.Type
+		switch n := node.(type) {
+		case *ast.Field:
+			t = p.Info.TypeOf(n.Type)
+		case *ast.ValueSpec:
+			if n.Type != nil {
+				t = p.Info.TypeOf(n.Type)
+			} else if len(n.Names) > 0 {
+				t = p.Info.TypeOf(n.Names[0])
+			}
+		case ast.Expr:
+			t = p.Info.TypeOf(n)
+		}
+		return t != nil && types.TypeString(t.Underlying(), nil) == typeString
+	}
+}
+
+// isNamed Reports whether t, or the type t points to, is the named type pkgPath.typeName.
+func isNamed(t types.Type, pkgPath, typeName string) bool {
+	if pointer, ok := t.(*types.Pointer); ok {
+		t = pointer.Elem()
+	}
+	named, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	return obj.Name() == typeName && obj.Pkg() != nil && obj.Pkg().Path() == pkgPath
+}
@@ -0,0 +1,48 @@
+package AstUtils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTypeCheckUsesImportPath(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/mod // comment\n\ngo 1.23\n",
+		"a/a.go": `package a
+
+type DB struct{}
+
+func (db *DB) Query() {}
+
+func Open() *DB { return &DB{} }
+
+func use() {
+	Open().Query()
+}
+`,
+	}
+	for name, src := range files {
+		filename := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pkg, err := LoadTypedPackage(filepath.Join(dir, "a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path := pkg.Types.Path(); path != "example.com/mod/a" {
+		t.Fatalf("package checked as %q, want example.com/mod/a", path)
+	}
+	if calls := pkg.SearchTyped(pkg.CallsMethod("example.com/mod/a", "DB", "Query")); len(calls) != 1 {
+		t.Errorf("found %d calls of DB.Query, want 1", len(calls))
+	}
+	if calls := pkg.SearchTyped(pkg.CallsFunction("example.com/mod/a", "Open")); len(calls) != 1 {
+		t.Errorf("found %d calls of Open, want 1", len(calls))
+	}
+}
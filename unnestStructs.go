@@ -1,68 +1,371 @@
 package AstUtils
 
 import (
+	"context"
+	"fmt"
 	"go/ast"
 	"go/token"
+	"go/types"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
+// UnnestOptions configures UnnestStructWithOptions. The zero value extracts structs the way UnnestStruct does.
+type UnnestOptions struct {
+	// Naming decides the names of the extracted types. Nil names them after their field, see FieldName.
+	Naming NamingStrategy
+	// Package holds the files the names of the extracted types must not collide with, besides the file itself.
+	// Usually that's the package containing the file.
+	Package *Package
+	// References decides whether the extracted structs are referenced by value or by pointer.
+	References ReferenceMode
+	// MergeIdentical declares a single type for structs with the same fields, types and tags. Positions and comments
+	// are ignored. Structs are compared before any struct nested inside them is extracted.
+	MergeIdentical bool
+	// Functions extracts the structs of parameters and results of functions and function types outside of structs as
+	// well, e.g. of func Handle(req struct{...}). Function literals inside function bodies are left alone. A struct name
+	// given to UnnestStructWithOptions restricts this to function types declared as the named type.
+	Functions bool
+	// SkipUnextractable leaves structs that can't be extracted inline, instead of returning an error.
+	SkipUnextractable bool
+}
+
+// ReferenceMode decides how the fields that held an extracted struct refer to the declared type.
+type ReferenceMode int
+
+const (
+	// AlwaysPointer refers to the extracted types by pointer, e.g. []struct{} becomes []*T. Structs that were already
+	// behind a pointer aren't wrapped into another one. This is what UnnestStruct does.
+	AlwaysPointer ReferenceMode = iota
+	// PreserveValue refers to the extracted types the way the structs were used, so values stay values. This keeps the
+	// zero values and the copying behavior of the fields unchanged.
+	PreserveValue
+	// PointerForFields refers to the types by pointer, if the struct was the type of a field, e.g. of a struct field or
+	// a parameter. Structs inside slices, arrays, maps and channels are referenced by value.
+	PointerForFields
+)
+
+// reference Returns the expression referring to the type name, which replaces the struct at path.
+func (m ReferenceMode) reference(name string, pos token.Pos, path Path) ast.Expr {
+	var t ast.Expr = &ast.Ident{
+		NamePos: pos,
+		Name:    name,
+	}
+	if _, ok := path.Parent().(*ast.StarExpr); ok || m == PreserveValue {
+		return t
+	}
+	if _, ok := path.Parent().(*ast.Field); m == PointerForFields && !ok {
+		return t
+	}
+	return &ast.StarExpr{
+		Star: pos,
+		X:    t,
+	}
+}
+
+// NamingStrategy Returns the name of the type extracted from the struct at path. The path holds the full ancestry of
+// the struct in the unmodified file. Names colliding with existing declarations get a number appended.
+type NamingStrategy func(node *ast.StructType, path Path) string
+
+// ExtractedStruct describes a named type declared by UnnestStructWithOptions. Fields holds the fields whose type now
+// refers to the declared type, starting with the field the struct was extracted from.
+type ExtractedStruct struct {
+	Name   string
+	Decl   *ast.GenDecl
+	Fields []*ast.Field
+}
+
+// Merged Returns the fields whose struct was identical to the extracted one and got replaced by a reference to it,
+// see UnnestOptions.MergeIdentical.
+func (e *ExtractedStruct) Merged() []*ast.Field {
+	return e.Fields[1:]
+}
+
+// FieldName Names an extracted type after the field holding the struct, e.g. Address for the field Address of User.
+// Fields declaring several names, like A, B struct{...}, share a single type named after the first name, as the names
+// had identical types before. Structs of unnamed fields are named after the closest named field or type containing
+// them, followed by Embedded for embedded fields, Param for parameters and Result for results, e.g. HandlerParam for
+// the struct in Handler func(struct{...}).
+func FieldName(node *ast.StructType, path Path) string {
+	return typeName(path, false)
+}
+
+// ParentFieldName Names an extracted type after the struct containing it followed by the field holding it, e.g.
+// UserAddress for the field Address of User. The name of a struct that is extracted itself is determined the same
+// way, so deeper nested structs get names like UserAddressGeo. Unnamed fields are handled like FieldName does.
+func ParentFieldName(node *ast.StructType, path Path) string {
+	return typeName(path, true)
+}
+
+// typeName Returns the name for the type at path, derived from the field holding it. If qualified is set, the name is
+// prefixed by the name of the struct or function type declaring the field, otherwise only names of unnamed fields
+// are. Returns an empty string, if no name can be derived.
+func typeName(path Path, qualified bool) string {
+	if len(path) == 0 {
+		return ""
+	}
+	switch parent := path.Parent().(type) {
+	case *ast.TypeSpec:
+		return parent.Name.Name
+	case *ast.FuncDecl:
+		return parent.Name.Name
+	}
+	i := len(path) - 1
+	for ; i >= 0; i-- {
+		if _, ok := path[i].Parent.(*ast.Field); ok {
+			break
+		}
+	}
+	if i < 0 {
+		return ""
+	}
+	field := path[i].Parent.(*ast.Field)
+	if len(field.Names) > 0 && !qualified {
+		return field.Names[0].Name
+	}
+	// path[i-1] steps from the FieldList to the field, path[i-2] from the struct or function type to the FieldList
+	owner := ""
+	if i >= 2 {
+		owner = typeName(path[:i-2], qualified)
+	}
+	if len(field.Names) > 0 {
+		if owner == "" {
+			return field.Names[0].Name
+		}
+		return owner + upperFirst(field.Names[0].Name)
+	}
+	if owner == "" {
+		return ""
+	}
+	if i >= 2 && path[i-2].Field != "Fields" && path[i-2].Field != "Methods" {
+		// Params, Results or TypeParams of a function type
+		return owner + strings.TrimSuffix(path[i-2].Field, "s")
+	}
+	return owner + "Embedded"
+}
+
+// upperFirst Returns s with its first letter in upper case.
+func upperFirst(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[size:]
+}
+
 // UnnestStruct Unnest structs that are contained inside other structs. If a name is given, only structs that are
-// embedded in the named one are considered otherwise all structs inside the file.
+// embedded in the named one are considered otherwise all structs inside the file. Structs that can't be extracted, see
+// UnnestStructWithOptions, stay inline.
 func UnnestStruct(structName *string, file *ast.File) {
-	var foundNodes []*FoundNodes
-	var completed = false
+	_ = UnnestStructContext(context.Background(), structName, file)
+}
+
+// UnnestStructContext Works like UnnestStruct, but stops once ctx is done. The file isn't modified, if the search for
+// nested structs was cancelled. Returns ctx.Err() in that case.
+func UnnestStructContext(ctx context.Context, structName *string, file *ast.File) error {
+	_, err := UnnestStructWithOptionsContext(ctx, structName, file, UnnestOptions{
+		SkipUnextractable: true,
+	})
+	return err
+}
+
+// UnnestStructWithOptions Works like UnnestStruct, but names the extracted types as configured by options. Names
+// already declared in the file or in options.Package, and names of predeclared identifiers, are disambiguated by
+// appending a number, starting with 2. Returns the declared types in the order they were added to the file.
+//
+// Structs are extracted from the types of fields of structs, including embedded fields and fields declaring several
+// names, and of parameters and results of function types inside structs, see UnnestOptions.Functions for other
+// functions. Structs inside pointers, slices, arrays, maps, channels and type arguments are extracted as well. If a
+// struct can't be extracted, because it isn't part of a type or refers to type parameters, an error is returned and the
+// file isn't modified, unless UnnestOptions.SkipUnextractable is set.
+func UnnestStructWithOptions(structName *string, file *ast.File, options UnnestOptions) ([]*ExtractedStruct, error) {
+	return UnnestStructWithOptionsContext(context.Background(), structName, file, options)
+}
+
+// UnnestStructWithOptionsContext Works like UnnestStructWithOptions, but stops once ctx is done. The file isn't
+// modified, if the search for nested structs was cancelled. Returns ctx.Err() in that case.
+func UnnestStructWithOptionsContext(ctx context.Context, structName *string, file *ast.File, options UnnestOptions) ([]*ExtractedStruct, error) {
+	naming := options.Naming
+	if naming == nil {
+		naming = FieldName
+	}
 	// Find all structs that are embedded inside another struct. This includes structs that are inside another struct
 	//and part of map, channels etc. For example chan Example struct{}, is externalized as well
-	SearchNodes(file, &foundNodes, []*ast.Node{}, func(n *ast.Node, parents []*ast.Node, completed *bool) bool {
-		if _, ok := (*n).(*ast.StructType); ok && len(parents) > 0 {
-			for _, parent := range parents {
-				if structName == nil {
-					return true
-				} else if par, ok := (*parent).(*ast.TypeSpec); ok && len(parents) > 0 && par.Name.Name == *structName {
-					return true
+	foundNodes, err := FindAllContext(ctx, file, func(node *ast.StructType, path Path) bool {
+		if len(path) == 0 {
+			return false
+		}
+		if structName == nil {
+			return true
+		}
+		spec := path.EnclosingTypeSpec()
+		return spec != nil && spec.Name.Name == *structName
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	taken := declaredNames(file)
+	if options.Package != nil {
+		for _, packageFile := range options.Package.Files {
+			for name := range declaredNames(packageFile.File) {
+				taken[name] = true
+			}
+		}
+	}
+	// Name all structs before the file is modified, so it stays untouched if a struct can't be named
+	var nested []Match[*ast.StructType]
+	var targets []*ExtractedStruct
+	var extracted []*ExtractedStruct
+	merged := map[*ast.StructType]bool{}
+	for _, node := range foundNodes {
+		//Only structs inside another struct are extracted, or inside function types if enabled
+		if !node.Path.IsInside("StructType") &&
+			!(options.Functions && node.Path.IsInside("FuncType") && !node.Path.IsInside("BlockStmt")) {
+			continue
+		}
+		if err := checkExtractable(node.Node, node.Path); err != nil {
+			if options.SkipUnextractable {
+				continue
+			}
+			return nil, err
+		}
+		// structs nested inside a merged struct are dropped together with it
+		if slices.ContainsFunc(node.Path, func(step PathStep) bool {
+			parent, ok := step.Parent.(*ast.StructType)
+			return ok && merged[parent]
+		}) {
+			continue
+		}
+		var target *ExtractedStruct
+		if options.MergeIdentical {
+			for _, candidate := range extracted {
+				if NodesEqual(candidate.Decl.Specs[0].(*ast.TypeSpec).Type, node.Node) {
+					target = candidate
+					merged[node.Node] = true
+					break
 				}
 			}
-
 		}
-		return false
-	}, &completed)
-
-	func() {
-		for _, node := range foundNodes {
-			func() {
-				//Search for parent struct. If found, replace inline struct whit newly generated struct type
-				for _, parent := range node.Parents {
-					if _, ok := (*parent).(*ast.StructType); ok {
-						var name string
-						for _, parent1 := range node.Parents {
-							if g, ok := (*parent1).(*ast.Field); ok {
-								name = g.Names[0].Name
-							}
-						}
-						v := &ast.GenDecl{
-							Tok: token.TYPE,
-							Specs: []ast.Spec{
-								&ast.TypeSpec{
-									Name: &ast.Ident{
-										Name: name,
-									},
-									Type: (*node.Node).(*ast.StructType),
-								},
-							},
-						}
-						t := &ast.StarExpr{
-							X: &ast.Ident{
+		if target == nil {
+			name := naming(node.Node, node.Path)
+			if !token.IsIdentifier(name) {
+				return nil, fmt.Errorf("invalid name %q for the struct at %s", name, node.Path)
+			}
+			name = uniqueName(name, taken)
+			target = &ExtractedStruct{
+				Name: name,
+				Decl: &ast.GenDecl{
+					Tok: token.TYPE,
+					Specs: []ast.Spec{
+						&ast.TypeSpec{
+							Name: &ast.Ident{
 								Name: name,
 							},
-						}
+							Type: node.Node,
+						},
+					},
+				},
+			}
+			extracted = append(extracted, target)
+		}
+		nested = append(nested, node)
+		targets = append(targets, target)
+	}
 
-						file.Decls = append(file.Decls, v)
-						ReplaceExprChild(node.Parents[0], t)
-						return
+	//Replace inline structs whit the newly generated struct types
+	for i, node := range nested {
+		t := options.References.reference(targets[i].Name, node.Node.Pos(), node.Path)
+		if ReplaceNode(node.Path, t) == nil {
+			targets[i].Fields = append(targets[i].Fields, node.Path.EnclosingField())
+		}
+	}
+	var declared []*ExtractedStruct
+	for _, e := range extracted {
+		if len(e.Fields) > 0 {
+			file.Decls = append(file.Decls, e.Decl)
+			declared = append(declared, e)
+		}
+	}
+	return declared, nil
+}
+
+// checkExtractable Returns an error, if the struct at path can't be replaced by a named type. That's the case for
+// structs outside of field types, like the type of a composite literal, and for structs referring to type parameters.
+func checkExtractable(node *ast.StructType, path Path) error {
+	if !isFieldType(path) {
+		return fmt.Errorf("the struct at %s isn't part of a field type", path)
+	}
+	typeParams := map[string]bool{}
+	for _, step := range path {
+		var list *ast.FieldList
+		switch parent := step.Parent.(type) {
+		case *ast.TypeSpec:
+			list = parent.TypeParams
+		case *ast.FuncType:
+			list = parent.TypeParams
+		}
+		if list == nil {
+			continue
+		}
+		for _, field := range list.List {
+			for _, name := range field.Names {
+				typeParams[name.Name] = true
+			}
+		}
+	}
+	if len(typeParams) == 0 {
+		return nil
+	}
+	if use, ok := FindFirst(node, func(ident *ast.Ident, path Path) bool {
+		return typeParams[ident.Name] && !isDeclaringIdent(path)
+	}); ok {
+		return fmt.Errorf("the struct at %s refers to the type parameter %s", path, use.Node.Name)
+	}
+	return nil
+}
+
+// declaredNames Returns the names declared at the top level of file. Blank identifiers and methods are skipped.
+func declaredNames(file *ast.File) map[string]bool {
+	names := map[string]bool{}
+	add := func(ident *ast.Ident) {
+		if ident != nil && ident.Name != "_" {
+			names[ident.Name] = true
+		}
+	}
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv == nil {
+				add(d.Name)
+			}
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					add(s.Name)
+				case *ast.ValueSpec:
+					for _, name := range s.Names {
+						add(name)
 					}
+				case *ast.ImportSpec:
+					add(s.Name)
 				}
-			}()
+			}
 		}
-	}()
+	}
+	return names
+}
+
+// uniqueName Returns name, or name followed by the lowest number starting with 2 that makes it unique, if name is
+// taken or predeclared. The returned name is marked as taken.
+func uniqueName(name string, taken map[string]bool) string {
+	unique := name
+	for i := 2; taken[unique] || types.Universe.Lookup(unique) != nil; i++ {
+		unique = name + strconv.Itoa(i)
+	}
+	taken[unique] = true
+	return unique
 }
 
 func ReplaceExprChild(decl *ast.Node, n ast.Expr) {
@@ -0,0 +1,347 @@
+package AstUtils
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// unnest Parses src, runs fn on the file and returns the formatted result.
+func unnest(t *testing.T, src string, fn func(file *ast.File) error) (string, error) {
+	t.Helper()
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "test.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fnErr := fn(file)
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, file); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String(), fnErr
+}
+
+func TestUnnestStructSkipsUnextractable(t *testing.T) {
+	src := `package p
+
+type G[T any] struct {
+	Inner struct{ V T }
+	Other struct{ X int }
+}
+`
+	result, err := unnest(t, src, func(file *ast.File) error {
+		UnnestStruct(nil, file)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result, "Inner struct{ V T }") || !strings.Contains(result, "Other *Other") {
+		t.Errorf("unexpected result:\n%s", result)
+	}
+
+	original, err := unnest(t, src, func(file *ast.File) error {
+		_, err := UnnestStructWithOptions(nil, file, UnnestOptions{})
+		return err
+	})
+	if err == nil {
+		t.Fatal("no error for a struct referring to a type parameter")
+	}
+	if strings.Contains(original, "*Other") {
+		t.Errorf("file modified despite the error:\n%s", original)
+	}
+}
+
+func TestUnnestStructFunctions(t *testing.T) {
+	src := `package p
+
+func Handle(req struct{ Y int }) struct{ Z int } {
+	f := func(local struct{ L int }) {}
+	_ = f
+	return struct{ Z int }{}
+}
+`
+	result, err := unnest(t, src, func(file *ast.File) error {
+		UnnestStruct(nil, file)
+		return nil
+	})
+	if err != nil || strings.Contains(result, "type ") {
+		t.Fatalf("UnnestStruct extracted structs of functions: %v\n%s", err, result)
+	}
+
+	result, err = unnest(t, src, func(file *ast.File) error {
+		_, err := UnnestStructWithOptions(nil, file, UnnestOptions{
+			Naming:     ParentFieldName,
+			References: PreserveValue,
+			Functions:  true,
+		})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"func Handle(req HandleReq) HandleResult {",
+		"type HandleReq struct{ Y int }",
+		"type HandleResult struct{ Z int }",
+		"func(local struct{ L int })",
+		"return struct{ Z int }{}",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("result doesn't contain %q:\n%s", want, result)
+		}
+	}
+}
+
+func TestUnnestStructNaming(t *testing.T) {
+	tests := []struct {
+		name   string
+		src    string
+		naming NamingStrategy
+		// pkg is the source of another file of the package, if set
+		pkg     string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "field name",
+			src:  "type User struct{ Address struct{ Street string } }",
+			want: []string{"Address *Address }", "type Address struct{ Street string }"},
+		},
+		{
+			name: "existing type",
+			src:  "type Address int\n\ntype User struct{ Address struct{ Street string } }",
+			want: []string{"Address *Address2 }", "type Address2 struct{ Street string }"},
+		},
+		{
+			name: "existing function and variable",
+			src:  "func Address() {}\n\nvar Address2 = 1\n\ntype User struct{ Address struct{ Street string } }",
+			want: []string{"Address *Address3 }", "type Address3 struct{ Street string }"},
+		},
+		{
+			name: "other file of the package",
+			src:  "type User struct{ Address struct{ Street string } }",
+			pkg:  "package p\n\ntype Address int\n",
+			want: []string{"Address *Address2 }", "type Address2 struct{ Street string }"},
+		},
+		{
+			name: "predeclared",
+			src:  "type User struct{ any struct{ X int } }",
+			want: []string{"any *any2 }", "type any2 struct{ X int }"},
+		},
+		{
+			name: "same field name twice",
+			src:  "type A struct{ Data struct{ X int } }\n\ntype B struct{ Data struct{ Y int } }",
+			want: []string{"type A struct{ Data *Data }", "type B struct{ Data *Data2 }", "type Data2 struct{ Y int }"},
+		},
+		{
+			name: "parameter",
+			src:  "type H struct{ F func(struct{ X int }) }",
+			want: []string{"F func(*FParam)", "type FParam struct{ X int }"},
+		},
+		{
+			name:   "parent field name",
+			src:    "type User struct{ Address struct{ Geo struct{ Lat float64 } } }",
+			naming: ParentFieldName,
+			want: []string{
+				"Address *UserAddress }",
+				"type UserAddress struct{ Geo *UserAddressGeo }",
+				"type UserAddressGeo struct{ Lat float64 }",
+			},
+		},
+		{
+			name:   "parent field name taken",
+			src:    "type UserAddress int\n\ntype User struct{ Address struct{ Street string } }",
+			naming: ParentFieldName,
+			want:   []string{"Address *UserAddress2 }", "type UserAddress2 struct{ Street string }"},
+		},
+		{
+			name:   "parent field name of parameter",
+			src:    "type H struct{ F func(struct{ X int }) (r struct{ Y int }) }",
+			naming: ParentFieldName,
+			want:   []string{"F func(*HFParam) (r *HFR)", "type HFParam struct{ X int }", "type HFR struct{ Y int }"},
+		},
+		{
+			name: "invalid name",
+			src:  "type User struct{ Address struct{ Street string } }",
+			naming: func(node *ast.StructType, path Path) string {
+				return ""
+			},
+			want:    []string{"Address struct{ Street string }"},
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			options := UnnestOptions{Naming: test.naming}
+			if test.pkg != "" {
+				fset := token.NewFileSet()
+				file, err := parser.ParseFile(fset, "other.go", test.pkg, 0)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if options.Package, err = NewPackage(fset, file); err != nil {
+					t.Fatal(err)
+				}
+			}
+			result, err := unnest(t, "package p\n\n"+test.src+"\n", func(file *ast.File) error {
+				_, err := UnnestStructWithOptions(nil, file, options)
+				return err
+			})
+			if test.wantErr && err == nil {
+				t.Fatalf("no error, result:\n%s", result)
+			}
+			if !test.wantErr && err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range test.want {
+				if !strings.Contains(result, want) {
+					t.Errorf("result doesn't contain %q:\n%s", want, result)
+				}
+			}
+		})
+	}
+}
+
+func TestUnnestStructMergeIdentical(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		merge bool
+		// want holds the names of the declared types, each followed by the names of the fields referring to it
+		want map[string][]string
+		// merged holds the number of merged fields of every declared type
+		merged map[string]int
+	}{
+		{
+			name:   "identical",
+			src:    "type A struct {\n\tP struct{ X int }\n\tQ struct{ X int }\n}",
+			merge:  true,
+			want:   map[string][]string{"P": {"P", "Q"}},
+			merged: map[string]int{"P": 1},
+		},
+		{
+			name:   "not merged",
+			src:    "type A struct {\n\tP struct{ X int }\n\tQ struct{ X int }\n}",
+			want:   map[string][]string{"P": {"P"}, "Q": {"Q"}},
+			merged: map[string]int{"P": 0, "Q": 0},
+		},
+		{
+			name:   "different tags",
+			src:    "type A struct {\n\tP struct{ X int `json:\"x\"` }\n\tQ struct{ X int }\n}",
+			merge:  true,
+			want:   map[string][]string{"P": {"P"}, "Q": {"Q"}},
+			merged: map[string]int{"P": 0, "Q": 0},
+		},
+		{
+			name:   "across types",
+			src:    "type A struct{ P struct{ X int } }\n\ntype B struct{ Q []struct{ X int } }",
+			merge:  true,
+			want:   map[string][]string{"P": {"P", "Q"}},
+			merged: map[string]int{"P": 1},
+		},
+		{
+			name:   "nested inside merged",
+			src:    "type A struct {\n\tP struct{ In struct{ Y int } }\n\tQ struct{ In struct{ Y int } }\n}",
+			merge:  true,
+			want:   map[string][]string{"P": {"P", "Q"}, "In": {"In"}},
+			merged: map[string]int{"P": 1, "In": 0},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var extracted []*ExtractedStruct
+			result, err := unnest(t, "package p\n\n"+test.src+"\n", func(file *ast.File) error {
+				var err error
+				extracted, err = UnnestStructWithOptions(nil, file, UnnestOptions{MergeIdentical: test.merge})
+				return err
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(extracted) != len(test.want) {
+				t.Fatalf("declared %d types, want %d:\n%s", len(extracted), len(test.want), result)
+			}
+			for _, e := range extracted {
+				var fields []string
+				for _, field := range e.Fields {
+					fields = append(fields, field.Names[0].Name)
+				}
+				if !slices.Equal(fields, test.want[e.Name]) {
+					t.Errorf("type %s is used by %v, want %v", e.Name, fields, test.want[e.Name])
+				}
+				if len(e.Merged()) != test.merged[e.Name] {
+					t.Errorf("type %s merged %d fields, want %d", e.Name, len(e.Merged()), test.merged[e.Name])
+				}
+				if !slices.Equal(e.Merged(), e.Fields[1:]) {
+					t.Errorf("merged fields of %s don't follow the first field", e.Name)
+				}
+			}
+			if _, err := parser.ParseFile(token.NewFileSet(), "result.go", result, 0); err != nil {
+				t.Errorf("result doesn't parse: %v\n%s", err, result)
+			}
+		})
+	}
+}
+
+func TestUnnestStructReferences(t *testing.T) {
+	src := `package p
+
+type Gen[V any] struct{ v V }
+
+type A struct {
+	Field   struct{ X int }
+	Slice   []struct{ X int }
+	Map     map[string]struct{ X int }
+	Arg     Gen[struct{ X int }]
+	Pointer *struct{ X int }
+	F       func(struct{ X int })
+}
+`
+	tests := []struct {
+		mode ReferenceMode
+		want []string
+	}{
+		{
+			mode: AlwaysPointer,
+			want: []string{
+				"Field   *Field", "Slice   []*Slice", "Map     map[string]*Map", "Arg     Gen[*Arg]",
+				"Pointer *Pointer", "F       func(*FParam)",
+			},
+		},
+		{
+			mode: PreserveValue,
+			want: []string{
+				"Field   Field", "Slice   []Slice", "Map     map[string]Map", "Arg     Gen[Arg]",
+				"Pointer *Pointer", "F       func(FParam)",
+			},
+		},
+		{
+			mode: PointerForFields,
+			want: []string{
+				"Field   *Field", "Slice   []Slice", "Map     map[string]Map", "Arg     Gen[Arg]",
+				"Pointer *Pointer", "F       func(*FParam)",
+			},
+		},
+	}
+	for _, test := range tests {
+		result, err := unnest(t, src, func(file *ast.File) error {
+			_, err := UnnestStructWithOptions(nil, file, UnnestOptions{References: test.mode})
+			return err
+		})
+		if err != nil {
+			t.Fatalf("mode %d: %v", test.mode, err)
+		}
+		for _, want := range test.want {
+			if !strings.Contains(result, want+"\n") {
+				t.Errorf("mode %d: result doesn't contain %q:\n%s", test.mode, want, result)
+			}
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package AstUtils
+
+import (
+	"context"
+	"go/ast"
+)
+
+// Visitor receives the nodes of a traversal twice. Enter is called before the children of a node are visited, Leave
+// after all of them have been visited. This allows bottom-up rewrites, e.g. handling the innermost anonymous struct
+// before the struct containing it.
+type Visitor interface {
+	// Enter is called in pre-order. The returned Action decides whether the children are visited.
+	Enter(node ast.Node, path Path) Action
+	// Leave is called in post-order, for every node whose Enter didn't return Stop. It's called for nodes whose
+	// children got skipped as well.
+	Leave(node ast.Node, path Path)
+}
+
+// VisitorFuncs implements Visitor using optional functions. A nil Enter continues with the children, a nil Leave is
+// ignored.
+type VisitorFuncs struct {
+	EnterFunc func(node ast.Node, path Path) Action
+	LeaveFunc func(node ast.Node, path Path)
+}
+
+func (v VisitorFuncs) Enter(node ast.Node, path Path) Action {
+	if v.EnterFunc == nil {
+		return Continue
+	}
+	return v.EnterFunc(node, path)
+}
+
+func (v VisitorFuncs) Leave(node ast.Node, path Path) {
+	if v.LeaveFunc != nil {
+		v.LeaveFunc(node, path)
+	}
+}
+
+// VisitNodes Traverses root and all nodes below it, calling Enter and Leave of the visitor. Once Enter returns Stop the
+// traversal ends immediately, without calling Leave for the ancestors of that node. Nodes may be replaced using
+// ReplaceNode while they are visited, as long as the length of the lists holding them isn't changed. Returns false if
+// the traversal was stopped.
+func VisitNodes(root ast.Node, visitor Visitor, opts ...SearchOption) bool {
+	completed, _ := walkPruned(context.Background(), root, Path{}, visitor.Enter, visitor.Leave, opts)
+	return completed
+}
+
+// VisitNodesContext Works like VisitNodes, but terminates the traversal once ctx is done, without calling Leave for
+// the pending nodes. Returns ctx.Err() if the traversal was cancelled.
+func VisitNodesContext(ctx context.Context, root ast.Node, visitor Visitor, opts ...SearchOption) error {
+	_, err := walkPruned(ctx, root, Path{}, visitor.Enter, visitor.Leave, opts)
+	return err
+}
@@ -0,0 +1,156 @@
+package AstUtils
+
+import (
+	"context"
+	"go/ast"
+	"iter"
+	"reflect"
+)
+
+// Walk Returns an iterator over root and all nodes below it, together with their path. Nodes are yielded while the
+// tree is traversed, so no result slice is built and breaking out of the loop terminates the traversal. Every path is
+// a copy, so the loop may keep it. See WalkShared to avoid the copies.
+func Walk(root ast.Node, opts ...SearchOption) iter.Seq2[ast.Node, Path] {
+	return ClonePaths(WalkShared(root, opts...))
+}
+
+// WalkShared Works like Walk, but yields the path shared by the traversal instead of a copy, which saves an
+// allocation per node. The path is only valid until the next iteration, use Path.Clone to keep it.
+func WalkShared(root ast.Node, opts ...SearchOption) iter.Seq2[ast.Node, Path] {
+	return func(yield func(ast.Node, Path) bool) {
+		walkPath(context.Background(), root, Path{}, yield, opts)
+	}
+}
+
+// Filter Returns an iterator that only yields the nodes of seq for which keep returns true. Filters can be chained.
+// The paths are passed on as they are, so they may be kept if seq is a Walk, but not if it's a WalkShared. Walk copies
+// the path of every node, including the ones the filter drops. ClonePaths(Filter(WalkShared(root), keep)) only copies
+// the paths of the kept nodes.
+func Filter(seq iter.Seq2[ast.Node, Path], keep func(node ast.Node, path Path) bool) iter.Seq2[ast.Node, Path] {
+	return func(yield func(ast.Node, Path) bool) {
+		for node, path := range seq {
+			if keep(node, path) && !yield(node, path) {
+				return
+			}
+		}
+	}
+}
+
+// ClonePaths Returns an iterator that yields the nodes of seq together with a copy of their path, so the paths of a
+// WalkShared may be kept.
+func ClonePaths(seq iter.Seq2[ast.Node, Path]) iter.Seq2[ast.Node, Path] {
+	return func(yield func(ast.Node, Path) bool) {
+		for node, path := range seq {
+			if !yield(node, path.Clone()) {
+				return
+			}
+		}
+	}
+}
+
+// walkPath Calls fn for node and all nodes below it in depth-first order. All calls share a single path, which is only
+// valid until fn returns, callers have to copy it to keep it. Returns false, if fn requested to stop the traversal or
+// ctx is done. The error is ctx.Err() in the latter case.
+func walkPath(ctx context.Context, node ast.Node, path Path, fn func(node ast.Node, path Path) bool, opts []SearchOption) (bool, error) {
+	return walkPruned(ctx, node, path, func(node ast.Node, path Path) Action {
+		if !fn(node, path) {
+			return Stop
+		}
+		return Continue
+	}, nil, opts)
+}
+
+// walkPruned Works like walkPath, but the Action returned by enter decides whether the children of a node are
+// visited. If leave isn't nil, it's called once the children of a node have been visited.
+func walkPruned(ctx context.Context, node ast.Node, path Path, enter func(node ast.Node, path Path) Action, leave func(node ast.Node, path Path), opts []SearchOption) (bool, error) {
+	w := &walker{
+		searchConfig: newSearchConfig(opts),
+		ctx:          ctx,
+		done:         ctx.Done(),
+		enter:        enter,
+		leave:        leave,
+		path:         path[:len(path):len(path)],
+	}
+	w.visitChild = w.visit
+	if w.allComments {
+		w.visitedComments = map[*ast.CommentGroup]bool{}
+	}
+	completed := w.walk(node)
+	return completed, w.err
+}
+
+// walker holds the state of a single traversal. path is a stack shared by all nodes, children push their step before
+// they are visited and pop it afterwards. parent is the node whose children are visited at the moment.
+type walker struct {
+	searchConfig
+	ctx             context.Context
+	done            <-chan struct{}
+	err             error
+	enter           func(node ast.Node, path Path) Action
+	leave           func(node ast.Node, path Path)
+	visitedComments map[*ast.CommentGroup]bool
+	path            Path
+	parent          ast.Node
+	// visitChild is bound once, so the traversal doesn't allocate a closure per node
+	visitChild func(child ast.Node, field string, index int) bool
+}
+
+func (w *walker) walk(node ast.Node) bool {
+	if isNilNode(node) {
+		return true
+	}
+	select {
+	case <-w.done:
+		w.err = w.ctx.Err()
+		return false
+	default:
+	}
+	if group, ok := node.(*ast.CommentGroup); ok && w.visitedComments != nil {
+		if w.visitedComments[group] {
+			return true
+		}
+		w.visitedComments[group] = true
+	}
+	// the capacity is limited, so appending to the path inside a callback can't overwrite the steps of other nodes
+	path := w.path[:len(w.path):len(w.path)]
+	action := w.enter(node, path)
+	if action == Stop {
+		return false
+	}
+	if action != SkipChildren {
+		w.parent = node
+		if !walkChildren(node, w.visitChild) {
+			return false
+		}
+		if file, ok := node.(*ast.File); ok && w.allComments && !visitList(file.Comments, "Comments", w.visitChild) {
+			return false
+		}
+	}
+	if w.leave != nil {
+		w.leave(node, path)
+	}
+	return true
+}
+
+// visit Pushes the step to child onto the path, visits child and pops the step again.
+func (w *walker) visit(child ast.Node, field string, index int) bool {
+	parent := w.parent
+	w.path = append(w.path, PathStep{
+		Parent: parent,
+		Field:  field,
+		Index:  index,
+	})
+	completed := w.walk(child)
+	w.path = w.path[:len(w.path)-1]
+	w.parent = parent
+	return completed
+}
+
+// isNilNode Reports whether n is nil or an interface holding a nil pointer, as produced for unset optional fields.
+func isNilNode(n ast.Node) bool {
+	if n == nil {
+		return true
+	}
+	v := reflect.ValueOf(n)
+	return v.Kind() == reflect.Pointer && v.IsNil()
+}
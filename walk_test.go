@@ -0,0 +1,141 @@
+package AstUtils
+
+import (
+	"flag"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// inspectNodes Returns the nodes ast.Inspect visits, followed by the comment groups of the file it doesn't visit, if
+// comments are included. That's the order Walk visits the nodes in.
+func inspectNodes(file *ast.File, comments bool) []ast.Node {
+	var nodes []ast.Node
+	visited := map[*ast.CommentGroup]bool{}
+	ast.Inspect(file, func(node ast.Node) bool {
+		if node != nil {
+			nodes = append(nodes, node)
+			if group, ok := node.(*ast.CommentGroup); ok {
+				visited[group] = true
+			}
+		}
+		return true
+	})
+	if comments {
+		for _, group := range file.Comments {
+			if visited[group] {
+				continue
+			}
+			nodes = append(nodes, group)
+			for _, comment := range group.List {
+				nodes = append(nodes, comment)
+			}
+		}
+	}
+	return nodes
+}
+
+// checkParity Compares the nodes visited by Walk and SearchNodes with the ones ast.Inspect visits, and checks that
+// the paths lead to the nodes.
+func checkParity(t *testing.T, name string, file *ast.File) {
+	t.Helper()
+	for _, comments := range []bool{false, true} {
+		var opts []SearchOption
+		if comments {
+			opts = append(opts, IncludeComments())
+		}
+		want := inspectNodes(file, comments)
+
+		var walked []ast.Node
+		for node, path := range Walk(file, opts...) {
+			walked = append(walked, node)
+			if len(path) > 0 {
+				slot, _, err := slotOf(path)
+				if err != nil || slot.Interface() != node {
+					t.Fatalf("%s: path %s doesn't lead to %T", name, path, node)
+				}
+			}
+		}
+		compareNodes(t, name+": Walk", walked, want)
+
+		var found []*FoundNodes
+		SearchNodes(file, &found, nil, func(node *ast.Node, parents []*ast.Node, completed *bool) bool {
+			return true
+		}, nil, opts...)
+		searched := make([]ast.Node, len(found))
+		for i, match := range found {
+			searched[i] = *match.Node
+			if len(match.Parents) != len(match.Path) {
+				t.Fatalf("%s: %d parents for a path of length %d", name, len(match.Parents), len(match.Path))
+			}
+		}
+		compareNodes(t, name+": SearchNodes", searched, want)
+	}
+}
+
+func compareNodes(t *testing.T, name string, got, want []ast.Node) {
+	t.Helper()
+	for i := 0; i < len(got) && i < len(want); i++ {
+		if got[i] != want[i] {
+			t.Fatalf("%s: node %d is %T, want %T", name, i, got[i], want[i])
+		}
+	}
+	if len(got) != len(want) {
+		t.Fatalf("%s: visited %d nodes, want %d", name, len(got), len(want))
+	}
+}
+
+// goroot enables checking all of GOROOT/src in TestWalkMatchesInspect, which takes more than a minute.
+var goroot = flag.Bool("goroot", false, "compare Walk with ast.Inspect for all sources of GOROOT, not only go/...")
+
+func TestWalkMatchesInspect(t *testing.T) {
+	root := filepath.Join(runtime.GOROOT(), "src")
+	if !*goroot {
+		root = filepath.Join(root, "go")
+	}
+	files := 0
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".go") {
+			return err
+		}
+		file, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.ParseComments)
+		if err != nil {
+			// testdata contains files with syntax errors on purpose
+			return nil
+		}
+		files++
+		checkParity(t, path, file)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files == 0 {
+		t.Skip("no sources found in", root)
+	}
+}
+
+func FuzzWalk(f *testing.F) {
+	for _, name := range []string{"walk.go", "search.go", "pattern.go"} {
+		src, err := os.ReadFile(name)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(src)
+	}
+	f.Add([]byte("package p\n\n// doc\ntype T[K comparable, V any] interface{ M(func(int) (K, V)) }\n\n/* free */\n"))
+	f.Add([]byte("package p\nimport (\n\t\"fmt\"\n\tx \"os\"\n)\nfunc f() { select { case <-c: default: }; goto L; L: }\n"))
+	f.Fuzz(func(t *testing.T, src []byte) {
+		file, err := parser.ParseFile(token.NewFileSet(), "fuzz.go", src, parser.ParseComments)
+		if err != nil {
+			return
+		}
+		checkParity(t, "fuzz.go", file)
+	})
+}